@@ -0,0 +1,145 @@
+package jobbigt
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Finds the cookie with the name set by the response. If set several times the last one is used, as it overrides the earlier ones.
+func findCookie(response *http.Response, name string) (*http.Cookie, *Result) {
+	var found *http.Cookie
+	for _, c := range response.Cookies() {
+		if c.Name == name {
+			found = c
+		}
+	}
+
+	if found == nil {
+		return nil, &Result{
+			Type:        Failure,
+			Description: fmt.Sprintf("response does not set cookie '%s'", name),
+			Err:         ErrValueMissing,
+		}
+	}
+
+	return found, nil
+}
+
+// Assert that the cookie with the name is set with the Secure attribute. A missing cookie or attribute results in a 'Failure'.
+func (r *Request) CookieIsSecure(name string) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		c, result := findCookie(response, name)
+		if result != nil {
+			return result
+		}
+
+		if !c.Secure {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("cookie '%s' is not secure", name),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the cookie with the name is set with the HttpOnly attribute. A missing cookie or attribute results in a 'Failure'.
+func (r *Request) CookieIsHttpOnly(name string) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		c, result := findCookie(response, name)
+		if result != nil {
+			return result
+		}
+
+		if !c.HttpOnly {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("cookie '%s' is not http only", name),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response sets exactly n cookies. All cookies are counted, including several set with the same name,
+// unlike the other cookie assertions which assert on the last cookie set with a name. A mismatch results in a 'Failure'.
+func (r *Request) CookieCount(n int) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if count := len(response.Cookies()); count != n {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected number of cookies, expected %d but received %d", n, count),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response does not set any cookies, e.g. for cookieless endpoints. Any cookie set results in a 'Failure'.
+func (r *Request) SetsNoCookies() *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if cookies := response.Cookies(); len(cookies) > 0 {
+			names := make([]string, len(cookies))
+			for i, c := range cookies {
+				names[i] = c.Name
+			}
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response sets cookies: %s", strings.Join(names, ", ")),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+func sameSiteString(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return "unset"
+}
+
+// Assert that the cookie with the name is set with the SameSite attribute of a certain mode.
+// A missing cookie, or a mismatch in received and expected mode, results in a 'Failure'.
+func (r *Request) CookieSameSite(name string, mode http.SameSite) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		c, result := findCookie(response, name)
+		if result != nil {
+			return result
+		}
+
+		if c.SameSite != mode {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected same site mode of cookie '%s', expected %s but received %s", name, sameSiteString(mode), sameSiteString(c.SameSite)),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
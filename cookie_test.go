@@ -0,0 +1,112 @@
+package jobbigt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCookieAttributeAssertions(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret", Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+	}))
+
+	for id, tc := range []struct {
+		Request         *Request
+		ExpectedFailure bool
+	}{
+		{
+			Request:         Get(testServer.URL).CookieIsSecure("session").CookieIsHttpOnly("session").CookieSameSite("session", http.SameSiteStrictMode),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).CookieIsSecure("theme"),
+			ExpectedFailure: true,
+		},
+		{
+			Request:         Get(testServer.URL).CookieIsHttpOnly("theme"),
+			ExpectedFailure: true,
+		},
+		{
+			Request:         Get(testServer.URL).CookieSameSite("session", http.SameSiteLaxMode),
+			ExpectedFailure: true,
+		},
+		{
+			Request:         Get(testServer.URL).CookieIsSecure("missing"),
+			ExpectedFailure: true,
+		},
+	} {
+		result := tc.Request.Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestCookieCountAssertion(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "first"})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+		if r.URL.Path == "/duplicate" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "second", Secure: true})
+		}
+	}))
+
+	for id, tc := range []struct {
+		Request         *Request
+		ExpectedFailure bool
+	}{
+		{
+			Request:         Get(testServer.URL).CookieCount(2),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).CookieCount(1),
+			ExpectedFailure: true,
+		},
+		{
+			Request:         Get(testServer.URL + "/duplicate").CookieCount(3).CookieIsSecure("session"),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).CookieIsSecure("session"),
+			ExpectedFailure: true,
+		},
+	} {
+		result := tc.Request.Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestSetsNoCookiesAssertion(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/cookie" {
+			http.SetCookie(w, &http.Cookie{Name: "tracking", Value: "id"})
+		}
+	}))
+
+	for id, tc := range []struct {
+		Request         *Request
+		ExpectedFailure bool
+	}{
+		{
+			Request:         Get(testServer.URL).SetsNoCookies(),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL + "/cookie").SetsNoCookies(),
+			ExpectedFailure: true,
+		},
+	} {
+		result := tc.Request.Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package jobbigt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Error returned by the faults of FaultDrop and FaultCorrupt.
+var ErrFaultInjected = errors.New("fault injected")
+
+// A fault injected into a request by FaultInjection, performing the request using the next round tripper, or not.
+type Fault func(next http.RoundTripper, request *http.Request) (*http.Response, error)
+
+// Returns a fault delaying the request by the duration before it is sent, or until the request is cancelled.
+func FaultDelay(d time.Duration) Fault {
+	return func(next http.RoundTripper, request *http.Request) (*http.Response, error) {
+		select {
+		case <-time.After(d):
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		}
+		return next.RoundTrip(request)
+	}
+}
+
+// Returns a fault dropping the connection without sending the request.
+func FaultDrop() Fault {
+	return func(next http.RoundTripper, request *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("%w: connection dropped", ErrFaultInjected)
+	}
+}
+
+// Returns a fault corrupting the connection once the response is received, failing reading the body halfway through.
+func FaultCorrupt() Fault {
+	return func(next http.RoundTripper, request *http.Request) (*http.Response, error) {
+		response, err := next.RoundTrip(request)
+		if err != nil {
+			return nil, err
+		}
+		if response.Body == nil {
+			response.Body = http.NoBody
+		}
+
+		b, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		response.Body = io.NopCloser(io.MultiReader(
+			bytes.NewReader(b[:len(b)/2]),
+			&errReader{fmt.Errorf("%w: connection corrupted", ErrFaultInjected)},
+		))
+		return response, nil
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (e *errReader) Read(p []byte) (int, error) {
+	return 0, e.err
+}
+
+type faultTransport struct {
+	next        http.RoundTripper
+	probability float64
+	faults      []Fault
+	random      *rand.Rand
+}
+
+func (t *faultTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if t.random.Float64() >= t.probability {
+		return t.next.RoundTrip(request)
+	}
+
+	fault := t.faults[t.random.Intn(len(t.faults))]
+	return fault(t.next, request)
+}
+
+// Inject a fault, chosen at random from the faults, into each attempt with the probability, e.g. to test retry logic.
+// The seed makes the injected faults reproducible. If no faults are given FaultDrop is used.
+func (r *Request) FaultInjection(probability float64, seed int64, faults ...Fault) *Request {
+	if len(faults) == 0 {
+		faults = []Fault{FaultDrop()}
+	}
+	r.faults = &faultTransport{
+		probability: probability,
+		faults:      faults,
+		random:      rand.New(rand.NewSource(seed)),
+	}
+	return r
+}
@@ -0,0 +1,65 @@
+package jobbigt
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFaultInjection(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("response body"))
+	}))
+
+	for id, tc := range []struct {
+		Fault Fault
+	}{
+		{
+			Fault: FaultDrop(),
+		},
+		{
+			Fault: FaultCorrupt(),
+		},
+	} {
+		result := Get(testServer.URL).
+			FaultInjection(1.0, 0, tc.Fault).
+			Run()
+
+		if result.Type != Error || !errors.Is(result.Err, ErrFaultInjected) {
+			t.Errorf("(%d) received unexpected result: %v", id, *result)
+		}
+	}
+
+	result := Get(testServer.URL).
+		FaultInjection(1.0, 0, FaultDelay(100*time.Millisecond)).
+		BodyEquals([]byte("response body")).
+		Run()
+
+	if result.Type != Success || result.Metrics.Duration < 100*time.Millisecond {
+		t.Errorf("expected a delayed success: %v", *result)
+	}
+
+	result = Get(testServer.URL).
+		FaultInjection(0, 0).
+		BodyEquals([]byte("response body")).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestFaultCorruptNilBody(t *testing.T) {
+	result := Get("http://placeholder").
+		Transport(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Request: request}, nil
+		})).
+		FaultInjection(1.0, 0, FaultCorrupt()).
+		Run()
+
+	if result.Type != Error || !errors.Is(result.Err, ErrFaultInjected) {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
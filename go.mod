@@ -2,4 +2,20 @@ module jobbigt
 
 go 1.22.2
 
-require github.com/google/uuid v1.6.0
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/getkin/kin-openapi v0.128.0
+	github.com/google/uuid v1.6.0
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	github.com/go-openapi/jsonpointer v0.21.0 // indirect
+	github.com/go-openapi/swag v0.23.0 // indirect
+	github.com/invopop/yaml v0.3.1 // indirect
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/mailru/easyjson v0.7.7 // indirect
+	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
+	github.com/perimeterx/marshmallow v1.1.5 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
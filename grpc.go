@@ -0,0 +1,51 @@
+package jobbigt
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Creates a request probing the standard gRPC health service of the server at the url, asserting a grpc-status of 0 (OK).
+// gRPC requires HTTP/2, which the client negotiates over TLS.
+func GRPCHealthCheck(url string) *Request {
+	// An empty, uncompressed, health check request message.
+	frame := []byte{0, 0, 0, 0, 0}
+
+	return Post(strings.TrimSuffix(url, "/")+"/grpc.health.v1.Health/Check").
+		Header("Content-Type", "application/grpc").
+		Header("TE", "trailers").
+		Body(frame).
+		GRPCStatus(0)
+}
+
+// Assert that the grpc-status of the response, read from the trailer or from the header of a trailers only response,
+// is of a certain value. A mismatch in received and expected, or a missing status, results in a 'Failure'.
+func (r *Request) GRPCStatus(expected int) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		status := response.Trailer.Get("Grpc-Status")
+		if status == "" {
+			status = response.Header.Get("Grpc-Status")
+		}
+
+		if status == "" {
+			return &Result{
+				Type:        Failure,
+				Description: "response has no grpc-status",
+				Err:         ErrValueMissing,
+			}
+		}
+
+		if status != fmt.Sprint(expected) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected grpc-status, expected %d but received %s: %s", expected, status, response.Trailer.Get("Grpc-Message")),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
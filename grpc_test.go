@@ -0,0 +1,47 @@
+package jobbigt
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGRPCHealthCheck(t *testing.T) {
+	for id, tc := range []struct {
+		Status          string
+		ExpectedFailure bool
+	}{
+		{
+			Status:          "0",
+			ExpectedFailure: false,
+		},
+		{
+			Status:          "14",
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/grpc.health.v1.Health/Check" || r.Header.Get("Content-Type") != "application/grpc" {
+				t.Errorf("received unexpected request: %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+			}
+
+			b, err := io.ReadAll(r.Body)
+			if err != nil || len(b) != 5 {
+				t.Errorf("received unexpected request message: %v", b)
+			}
+
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Trailer", "Grpc-Status")
+			// A serving health check response message.
+			w.Write([]byte{0, 0, 0, 0, 2, 0x08, 0x01})
+			w.Header().Set("Grpc-Status", tc.Status)
+		}))
+
+		result := GRPCHealthCheck(testServer.URL).Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
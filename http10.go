@@ -0,0 +1,106 @@
+package jobbigt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// A round tripper sending requests using HTTP/1.0 over a new connection per request, which the standard transport
+// cannot as it always speaks HTTP/1.1 or HTTP/2.
+type http10Transport struct {
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+	tlsConfig *tls.Config
+}
+
+func (t *http10Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	port := request.URL.Port()
+	if port == "" {
+		port = "80"
+		if request.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	ctx := request.Context()
+	conn, err := t.dial(ctx, "tcp", net.JoinHostPort(request.URL.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+
+	if request.URL.Scheme == "https" {
+		config := &tls.Config{}
+		if t.tlsConfig != nil {
+			config = t.tlsConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = request.URL.Hostname()
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			stop()
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "%s %s HTTP/1.0\r\nHost: %s\r\n", request.Method, request.URL.RequestURI(), host)
+	request.Header.WriteSubset(w, map[string]bool{"Host": true, "Content-Length": true})
+	if len(body) > 0 || request.Method == http.MethodPost || request.Method == http.MethodPut || request.Method == http.MethodPatch {
+		fmt.Fprintf(w, "Content-Length: %s\r\n", strconv.Itoa(len(body)))
+	}
+	w.WriteString("\r\n")
+	w.Write(body)
+	if err := w.Flush(); err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, err
+	}
+	response.Body = &connClosingBody{ReadCloser: response.Body, conn: conn, stop: stop}
+	return response, nil
+}
+
+// Closes the connection along with the body, as HTTP/1.0 connections are not reused.
+type connClosingBody struct {
+	io.ReadCloser
+	conn net.Conn
+	stop func() bool
+}
+
+func (b *connClosingBody) Close() error {
+	b.stop()
+	b.ReadCloser.Close()
+	return b.conn.Close()
+}
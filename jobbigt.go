@@ -2,13 +2,34 @@ package jobbigt
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
+	"mime"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
 	"time"
+	"unicode/utf8"
 
+	"github.com/andybalholm/brotli"
 	"github.com/google/uuid"
 )
 
@@ -23,25 +44,137 @@ const (
 	Skip
 	Repeat
 	NoTest
+	Timeout
 )
 
+func (t ResultType) String() string {
+	switch t {
+	case Success:
+		return "Success"
+	case Failure:
+		return "Failure"
+	case Stop:
+		return "Stop"
+	case Error:
+		return "Error"
+	case Skip:
+		return "Skip"
+	case Repeat:
+		return "Repeat"
+	case NoTest:
+		return "NoTest"
+	case Timeout:
+		return "Timeout"
+	}
+	return fmt.Sprintf("ResultType(%d)", int(t))
+}
+
+// Encodes the result type as its name, e.g. in json.
+func (t ResultType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// Decodes a result type from its name.
+func (t *ResultType) UnmarshalText(text []byte) error {
+	for candidate := Success; candidate <= Timeout; candidate++ {
+		if candidate.String() == string(text) {
+			*t = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown result type '%s'", text)
+}
+
+// Errors set on results when a request is not valid.
+var (
+	ErrURLRequired    = errors.New("url is required")
+	ErrMethodRequired = errors.New("method is required")
+)
+
+// Error set on results of assertions when the value asserted on is missing from the response, see SkipIfMissing.
+var ErrValueMissing = errors.New("value missing")
+
+// Error set on results when the response body exceeds the size set by MaxBodySize.
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// Error set on results when a redirect is not allowed by RestrictRedirects.
+var ErrRedirectNotAllowed = errors.New("redirect not allowed")
+
 type Result struct {
 	Type           ResultType
 	Description    string
 	DownStreamArgs map[string]string
+	// The underlying error, if any, allowing checks using errors.Is. Not included when encoded as json.
+	Err error `json:"-"`
+	// Metrics of the run producing the result, set by Request.Run.
+	Metrics *Metrics
+	// Descriptions of the failing assertions marked AsWarning, which did not fail the request, set by Request.Run.
+	Warnings []string
+}
+
+// Metrics collected while running a request.
+type Metrics struct {
+	// Number of attempts made, including retries and repeats.
+	Attempts int
+	// Length of the last received response body once decoded according to its Content-Encoding, rather than the bytes on the wire.
+	BytesReceived int
+	// Status code of the last received response, 0 if no response was received.
+	StatusCode int
+	// Number of requests sent, including retries, repeats and any requests sent by assertions.
+	NetworkCalls int
+	// Total duration of the run.
+	Duration time.Duration
 }
 
 func (r *Result) Error() string {
-	if r.Type == Error {
+	if r.Type == Error || r.Type == Timeout {
 		return r.Description
 	}
 	return ""
 }
 
+func (r *Result) Unwrap() error {
+	return r.Err
+}
+
+// Returns the process exit code for a result, 0 on success and a distinct non zero code for each other result type.
+func ExitCode(result *Result) int {
+	switch result.Type {
+	case Success:
+		return 0
+	case Failure:
+		return 1
+	case Error:
+		return 2
+	case Stop:
+		return 3
+	case Skip:
+		return 4
+	case Repeat:
+		return 5
+	case NoTest:
+		return 6
+	case Timeout:
+		return 7
+	}
+	return 255
+}
+
+// Prints a summary of the result and exits the process with the exit code of the result.
+func RunAndExit(result *Result) {
+	if result.Description != "" {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", result.Type, result.Description)
+	} else {
+		fmt.Fprintln(os.Stderr, result.Type)
+	}
+	os.Exit(ExitCode(result))
+}
+
 func AnnotateResult(r *Result, desc string) *Result {
 	return &Result{
 		Type:        r.Type,
 		Description: fmt.Sprintf("%s: %s", desc, r.Description),
+		Err:         r.Err,
 	}
 }
 
@@ -49,6 +182,34 @@ func AnnotateResult(r *Result, desc string) *Result {
 type RequestGroup struct {
 	id       string
 	requests []*Request
+	sampled  bool
+	fraction float64
+	seed     int64
+	// Transport shared by the requests, set by ShareConnections.
+	transport         *http.Transport
+	report            *Report
+	times             int
+	continueOnFailure bool
+	interval          time.Duration
+	next              time.Time
+	// Called with the result of each request as it completes.
+	observe func(id string, result *Result)
+	// Only requests with any of the tags are run, set by RunTagged.
+	tags []string
+	// Assertion sets applied to every request of the group, set by ApplyToAll.
+	applied []*Request
+	// Writer receiving the result of each request as a line of json, set by StreamNDJSON.
+	ndjson io.Writer
+	// Encoder writing to ndjson during a run, and the first error writing to it.
+	encoder   *json.Encoder
+	streamErr error
+}
+
+// Share a single transport, and thereby its connection pool, between all requests of the group not having a transport of their own.
+// Requests setting transport level options, such as TLSConfig, UnixSocket or HTTP10, keep using a transport of their own.
+func (rq *RequestGroup) ShareConnections() *RequestGroup {
+	rq.transport = http.DefaultTransport.(*http.Transport).Clone()
+	return rq
 }
 
 func (rq *RequestGroup) Id(id string) *RequestGroup {
@@ -56,40 +217,327 @@ func (rq *RequestGroup) Id(id string) *RequestGroup {
 	return rq
 }
 
+// Only run a random fraction, between 0 and 1, of the requests. The requests are selected deterministically from the seed and the rest are skipped.
+func (rq *RequestGroup) Sample(fraction float64, seed int64) *RequestGroup {
+	rq.sampled = true
+	rq.fraction = min(max(fraction, 0), 1)
+	rq.seed = seed
+	return rq
+}
+
+func (rq *RequestGroup) selected() []*Request {
+	requests := rq.requests
+	if len(rq.tags) > 0 {
+		requests = nil
+		for _, r := range rq.requests {
+			if slices.ContainsFunc(r.tags, func(tag string) bool { return slices.Contains(rq.tags, tag) }) {
+				requests = append(requests, r)
+			}
+		}
+	}
+
+	if !rq.sampled {
+		return requests
+	}
+
+	n := int(math.Round(rq.fraction * float64(len(requests))))
+	indices := rand.New(rand.NewSource(rq.seed)).Perm(len(requests))[:n]
+	slices.Sort(indices)
+
+	sampled := make([]*Request, 0, n)
+	for _, i := range indices {
+		sampled = append(sampled, requests[i])
+	}
+	return sampled
+}
+
+// Runs the group like Run, but only the requests tagged with any of the tags. The other requests are skipped.
+func (rq *RequestGroup) RunTagged(tags ...string) *Result {
+	rq.tags = tags
+	defer func() {
+		rq.tags = nil
+	}()
+
+	return rq.Run()
+}
+
+// Run the group the given number of times, e.g. for soak testing. Unlike the iterations of a request every run is performed,
+// each running all requests. Any value below 1 will be ignored and set to the default value of 1.
+func (rq *RequestGroup) Repeat(times int) *RequestGroup {
+	if times >= 1 {
+		rq.times = times
+	}
+	return rq
+}
+
+// Limit the rate at which the requests are started to perSecond, pacing the requests evenly. Any value not above 0 removes the limit.
+func (rq *RequestGroup) RateLimit(perSecond float64) *RequestGroup {
+	rq.interval = 0
+	if perSecond > 0 {
+		rq.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return rq
+}
+
+// Waits until the next request may be started according to the rate limit.
+func (rq *RequestGroup) pace() {
+	if rq.interval == 0 {
+		return
+	}
+
+	time.Sleep(time.Until(rq.next))
+	rq.next = time.Now().Add(rq.interval)
+}
+
+// Set whether to continue with the remaining runs of a repeated group after a run with failing requests. Default false.
+func (rq *RequestGroup) ContinueOnFailure(continueOnFailure bool) *RequestGroup {
+	rq.continueOnFailure = continueOnFailure
+	return rq
+}
+
+// Runs the requests in order, as many times as set by Repeat. The downstream args of each request can be referenced by later requests
+// using placeholders of the form {{req.<id>.<key>}} in their url, headers and body.
+// A single run results in 'Success' whatever the results of the requests, which are available from Report, while a 'Skip' skips the rest
+// of the group. When repeated, a run with a request resulting in 'Failure', 'Error' or 'Timeout' is a failed run, and any failed run
+// results in a 'Failure' of the group. A request resulting in 'Stop' halts the group, including any remaining runs, and the group results in 'Stop'.
 func (rq *RequestGroup) Run() *Result {
-	for _, r := range rq.requests {
+	return rq.RunContext(context.Background())
+}
+
+// Runs the group like Run, until the context is cancelled. A cancelled context stops the group before the next request,
+// and cancels any request in flight, resulting in an 'Error'.
+func (rq *RequestGroup) RunContext(ctx context.Context) *Result {
+	rq.report = &Report{}
+	rq.encoder, rq.streamErr = nil, nil
+	if rq.ndjson != nil {
+		rq.encoder = json.NewEncoder(rq.ndjson)
+	}
+
+	result := rq.run(ctx)
+	if rq.streamErr != nil && result.Type == Success {
+		result = &Result{
+			Type:        Error,
+			Description: fmt.Sprintf("failed to stream results: %s", rq.streamErr.Error()),
+			Err:         rq.streamErr,
+		}
+	}
+	rq.report.Result = result
+	return result
+}
+
+func (rq *RequestGroup) run(ctx context.Context) *Result {
+	times := max(rq.times, 1)
+	var failures []string
+	for i := 1; i <= times; i++ {
+		result, failed := rq.runOnce(ctx)
+		if times == 1 || result.Type != Success || ctx.Err() != nil {
+			return result
+		}
+
+		if len(failed) > 0 {
+			failures = append(failures, fmt.Sprintf("run %d: failed requests %s", i, strings.Join(failed, ", ")))
+			if !rq.continueOnFailure {
+				break
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return &Result{
+			Type:        Failure,
+			Description: fmt.Sprintf("failed %d of %d runs, %s", len(failures), times, strings.Join(failures, ", ")),
+		}
+	}
+
+	return &Result{
+		Type: Success,
+	}
+}
+
+// Write the result of each request to the writer as it completes, as one json object per line, e.g. for piping to a log aggregator.
+// Each line decodes into a RequestResult. A failing write stops the stream and results in an 'Error' of an otherwise successful group.
+func (rq *RequestGroup) StreamNDJSON(w io.Writer) *RequestGroup {
+	rq.ndjson = w
+	return rq
+}
+
+// Runs the group in the background, emitting the result of each request on the returned channel as it completes.
+// The channel is closed when the run is complete, after which the report, including the result of the group, is available.
+// The channel must be drained.
+func (rq *RequestGroup) RunStream() <-chan *Result {
+	results := make(chan *Result)
+	go func() {
+		defer close(results)
+
+		rq.observe = func(id string, result *Result) {
+			results <- result
+		}
+		defer func() {
+			rq.observe = nil
+		}()
+
+		rq.Run()
+	}()
+	return results
+}
+
+// Runs the requests once, returning the result of the run and the ids of the requests resulting in 'Failure', 'Error' or 'Timeout'.
+func (rq *RequestGroup) runOnce(ctx context.Context) (*Result, []string) {
+	args := map[string]map[string]string{}
+	upstream := map[string]string{}
+	var failed []string
+	for _, r := range rq.selected() {
+		rq.pace()
+		if err := ctx.Err(); err != nil {
+			return &Result{
+				Type:        Error,
+				Description: fmt.Sprintf("group cancelled before request %s: %s", r.id, err.Error()),
+				Err:         err,
+			}, failed
+		}
+		r.groupArgs = args
+		r.ctx = ctx
+		r.upstreamArgs = upstream
+		shared := rq.transport != nil && r.transport == nil && !r.hasTransportOptions()
+		if shared {
+			r.transport = rq.transport
+		}
 		result := r.Run()
+		r.groupArgs = nil
+		r.ctx = nil
+		r.upstreamArgs = nil
+		if shared {
+			r.transport = nil
+		}
+		args[r.id] = result.DownStreamArgs
+		upstream = incomingArgs(upstream, []any{result.DownStreamArgs})
+		rq.report.add(r.id, result)
+		if rq.encoder != nil && rq.streamErr == nil {
+			rq.streamErr = rq.encoder.Encode(rq.report.Results[len(rq.report.Results)-1])
+		}
+		if rq.observe != nil {
+			rq.observe(r.id, result)
+		}
 		if result.Type == Skip {
 			return &Result{
 				Type:        Skip,
 				Description: fmt.Sprintf("Skipped caused by request %s", r.id),
-			}
+			}, failed
+		}
+
+		if result.Type == Stop {
+			return &Result{
+				Type:        Stop,
+				Description: fmt.Sprintf("Stopped by request %s", r.id),
+			}, failed
+		}
+
+		if result.Type == Failure || result.Type == Error || result.Type == Timeout {
+			failed = append(failed, r.id)
 		}
 	}
 
 	return &Result{
 		Type: Success,
-	}
+	}, failed
+}
+
+// Returns the report of the most recent run, nil if the group has not been run.
+func (rq *RequestGroup) Report() *Report {
+	return rq.report
 }
 
 func (rq *RequestGroup) AddRequest(r *Request) {
+	r.Apply(rq.applied...)
 	rq.requests = append(rq.requests, r)
 }
 
+// Add the assertions of the given sets to every request of the group, both those already added and those added later. See Apply.
+func (rq *RequestGroup) ApplyToAll(sets ...*Request) *RequestGroup {
+	for _, r := range rq.requests {
+		r.Apply(sets...)
+	}
+	rq.applied = append(rq.applied, sets...)
+	return rq
+}
+
 type Request struct {
 	id              string
 	url             string
+	urlParts        *url.URL
 	method          string
 	body            []byte
+	bodyFunc        func(iteration int) []byte
+	bodyErr         error
+	warnings        []string
+	seed            int64
+	seeded          bool
+	random          *rand.Rand
+	gzipBody        bool
 	headers         http.Header
 	sleep           time.Duration
 	timeout         int
 	iterations      int
+	retryOn         []int
+	retryOnError    bool
+	retried         bool
+	backoffBase     time.Duration
+	backoffMax      time.Duration
+	tlsConfig       *tls.Config
+	unixSocket      string
+	http10          bool
+	dialTimeout     time.Duration
+	headerTimeout   time.Duration
+	connDeadline    time.Duration
+	rawPath         string
+	checkRedirect   func(request *http.Request, via []*http.Request) error
+	transport       http.RoundTripper
+	faults          *faultTransport
+	vars            map[string]any
+	attempt         int
+	cacheHeaders    map[string]string
+	groupArgs       map[string]map[string]string
+	upstreamArgs    map[string]string
+	incomingArgs    map[string]string
+	ctx             context.Context
+	timeToFirstByte time.Duration
+	statusCode      int
+	connReused      bool
+	awsCredentials  *awsCredentials
+	modifyRequest   func(request *http.Request)
+	modifyResponse  func(response *http.Response)
+	extracted       map[string]string
+	assertAfterTest bool
+	tags            []string
+	latencies       []time.Duration
+	extraCalls      int
 	responseBody    []byte
+	rawBody         []byte
+	dumpWriter      io.Writer
+	idempotency     *idempotency
+	requestDump     []byte
+	responseDump    []byte
+	skipBodyRead    bool
+	normalizeJson   bool
+	maxBodySize     int64
+	streamJsonErr   error
 	preRequestFunc  func() *Result
-	testFunc        func(respone *http.Response, args ...any) Result
+	testFuncs       []func(respone *http.Response, args ...any) Result
 	postRequestFunc func(testResult *Result) *Result
-	assertions      []func(response *http.Response) *Result
+	onAttempt       func(attempt int, result *Result)
+	resultMapper    func(result *Result) *Result
+	assertions      []*assertion
+}
+
+type assertion struct {
+	label         string
+	readsBody     bool
+	streamsBody   bool
+	skipIfMissing bool
+	warning       bool
+	// Only extracts a downstream arg, not counted as testing the response.
+	extracts bool
+	check    func(r *Request, response *http.Response) *Result
 }
 
 func newRequest(url, method string) *Request {
@@ -114,24 +562,150 @@ func Post(url string) *Request {
 	return newRequest(url, http.MethodPost)
 }
 
+// Creates a new OPTIONS request.
+func Options(url string) *Request {
+	return newRequest(url, http.MethodOptions)
+}
+
+// Polls the url using GET requests until it responds with a 2xx status code, retrying on errors such as a refused connection.
+// Intended for waiting on a dependency at the start of a suite. Not responding with a 2xx within the duration results in a 'Failure',
+// an attempt in flight at the deadline is cancelled.
+func WaitForURL(url string, within time.Duration) *Result {
+	deadline := time.Now().Add(within)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	var last *Result
+
+	r := Get(url).
+		Iterations(math.MaxInt).
+		Sleep(100 * time.Millisecond).
+		PreRequest(func() *Result {
+			if last != nil && time.Now().After(deadline) {
+				return &Result{
+					Type:        Failure,
+					Description: fmt.Sprintf("%s not ready within %s, last attempt: %s", url, within, last.Description),
+				}
+			}
+			return &Result{
+				Type: Success,
+			}
+		}).
+		OnAttempt(func(attempt int, result *Result) {
+			last = result
+		}).
+		Test(func(response *http.Response, args ...any) Result {
+			if response.StatusCode < 200 || response.StatusCode > 299 {
+				return Result{
+					Type:        Repeat,
+					Description: fmt.Sprintf("received status code %d", response.StatusCode),
+				}
+			}
+			return Result{
+				Type: Success,
+			}
+		})
+	r.retryOnError = true
+	r.ctx = ctx
+
+	return r.Run()
+}
+
 // Set request id.
 func (r *Request) Id(id string) *Request {
 	r.id = id
 	return r
 }
 
+// Add tags to the request, used to select which requests of a group to run using RunTagged.
+func (r *Request) Tag(tags ...string) *Request {
+	r.tags = append(r.tags, tags...)
+	return r
+}
+
 // Set request body.
 func (r *Request) Body(body []byte) *Request {
 	r.body = body
 	return r
 }
 
+// Set the request body to the value encoded as json, and the Content-Type header to application/json.
+// A value which can not be encoded results in an 'Error' when run.
+func (r *Request) JsonBody(v any) *Request {
+	body, err := json.Marshal(v)
+	r.bodyErr = nil
+	if err != nil {
+		r.bodyErr = fmt.Errorf("failed to marshal json body: %w", err)
+	}
+	r.body = body
+	r.headers.Set("Content-Type", "application/json")
+	return r
+}
+
+// Compress the request body using gzip and set the Content-Encoding header to gzip. The compression is applied last,
+// after any placeholders in the body are expanded, regardless of the order the body is set in.
+func (r *Request) GzipBody() *Request {
+	r.gzipBody = true
+	return r
+}
+
+func gzipCompress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(b); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Set the seed of the random number generator of the request, returned by Rand, which is reseeded at the start of each run.
+// Makes randomized bodies, e.g. produced by BodyFunc, reproducible.
+func (r *Request) Seed(n int64) *Request {
+	r.seed = n
+	r.seeded = true
+	r.random = rand.New(rand.NewSource(n))
+	return r
+}
+
+// Returns the random number generator of the request, seeded using Seed or else by the current time.
+func (r *Request) Rand() *rand.Rand {
+	if r.random == nil {
+		r.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return r.random
+}
+
+// Set a function producing the request body, called on each iteration with the iteration number starting at 1.
+// Takes precedence over Body.
+func (r *Request) BodyFunc(fn func(iteration int) []byte) *Request {
+	r.bodyFunc = fn
+	return r
+}
+
+// Set the path of the request line as is, bypassing any cleaning or escaping of the url path, e.g. //foo/../bar.
+// A path beginning with // is sent in absolute form including the scheme and host.
+func (r *Request) RawPath(p string) *Request {
+	r.rawPath = p
+	return r
+}
+
 // Set request header key value pair.
 func (r *Request) Header(key, value string) *Request {
 	r.headers.Add(key, value)
 	return r
 }
 
+// Replace all request headers with the given header set. A Host header sets the host of the request.
+func (r *Request) WithHeaders(h http.Header) *Request {
+	r.headers = h.Clone()
+	if r.headers == nil {
+		r.headers = http.Header{}
+	}
+	return r
+}
+
 // TODO: More types of authorization headers.
 
 // Set basic auth header.
@@ -140,6 +714,58 @@ func (r *Request) BasicAuth(username, password string) *Request {
 	return r
 }
 
+// Set the Authorization header to the bearer token, replacing any previous value. The token may contain placeholders,
+// e.g. {{arg:token}} for a token extracted by an earlier request.
+func (r *Request) BearerToken(token string) *Request {
+	r.headers.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// Set the If-None-Match header, making the request conditional on the etag.
+func (r *Request) IfNoneMatch(etag string) *Request {
+	r.headers.Set("If-None-Match", etag)
+	return r
+}
+
+type idempotency struct {
+	header   string
+	fromBody bool
+	key      string
+}
+
+func (i *idempotency) keyFor(body []byte) string {
+	if i.fromBody {
+		sum := sha256.Sum256(body)
+		return hex.EncodeToString(sum[:])
+	}
+
+	if i.key == "" {
+		i.key = uuid.NewString()
+	}
+	return i.key
+}
+
+// Set an idempotency key in the header, Idempotency-Key if empty, reused by all attempts of a run.
+// If autoFromBody is set the key is the SHA-256 digest of the body, making it the same across runs of the same body,
+// otherwise a random key is generated for each run.
+func (r *Request) IdempotencyKey(autoFromBody bool, header string) *Request {
+	if header == "" {
+		header = "Idempotency-Key"
+	}
+	r.idempotency = &idempotency{
+		header:   header,
+		fromBody: autoFromBody,
+	}
+	return r
+}
+
+// Set the Accept header to the media types, in order of preference, replacing any previous value.
+// Quality values are passed as parameters of the media types, e.g. "application/xml;q=0.9".
+func (r *Request) Accept(mimeTypes ...string) *Request {
+	r.headers.Set("Accept", strings.Join(mimeTypes, ", "))
+	return r
+}
+
 // Set the duration to sleep between iterations.
 // Default no sleep.
 func (r *Request) Sleep(sleep time.Duration) *Request {
@@ -147,7 +773,7 @@ func (r *Request) Sleep(sleep time.Duration) *Request {
 	return r
 }
 
-// Set request timeout. A request timing out will result in a result with the type Error.
+// Set request timeout in seconds. A request timing out will result in a result with the type Timeout.
 func (r *Request) Timeout(timeout int) *Request {
 	r.timeout = timeout
 	return r
@@ -163,141 +789,1508 @@ func (r *Request) Iterations(iterations int) *Request {
 	return r
 }
 
-func (r *Request) perform() (*http.Response, error) {
-	var reader io.Reader
-	if r.body != nil {
-		reader = bytes.NewReader(r.body)
-	}
-
-	request, err := http.NewRequest(r.method, r.url, reader)
-	if err != nil {
-		return nil, err
-	}
-	request.Header = r.headers
-
-	c := &http.Client{
-		Timeout: time.Duration(r.timeout) * time.Second,
-	}
+// Set status codes which, when received, automatically re-runs the request. Each retry consumes an iteration and honors the sleep duration,
+// or the backoff set by RetryBackoff. If the iterations are exceeded the result type will be Failure.
+func (r *Request) RetryOn(codes ...int) *Request {
+	r.retryOn = append(r.retryOn, codes...)
+	return r
+}
 
-	return c.Do(request)
+// Set an exponential backoff between retries, used instead of the sleep duration. The first retry waits base and each consecutive
+// retry doubles the wait, capped at max.
+func (r *Request) RetryBackoff(base, max time.Duration) *Request {
+	r.backoffBase = base
+	r.backoffMax = max
+	return r
 }
 
-func (r *Request) readBody(response *http.Response) error {
-	b, err := io.ReadAll(response.Body)
-	if err != nil {
-		return err
+// Returns the wait before the nth consecutive retry according to the backoff.
+func (r *Request) backoff(n int) time.Duration {
+	d := r.backoffBase
+	for i := 1; i < n && d < r.backoffMax; i++ {
+		d *= 2
 	}
+	return min(d, r.backoffMax)
+}
 
-	r.responseBody = b
-
-	return nil
+// Set the TLS configuration used by the client, e.g. to trust a custom root CA.
+func (r *Request) TLSConfig(config *tls.Config) *Request {
+	r.tlsConfig = config
+	return r
 }
 
-// Performs the request, any pre-request/post-request functions, the test and assertions.
-func (r *Request) Run(args ...any) *Result {
-	if r.url == "" {
-		return &Result{
-			Type:        Error,
-			Description: "url is required",
-		}
-	} else if r.method == "" {
-		return &Result{
-			Type:        Error,
-			Description: "method is required",
+// Restrict which redirects are followed. If sameHost is set redirects to another host than the one of the request are not allowed,
+// if allowDowngrade is not set redirects from https to http are not allowed. A disallowed redirect results in an 'Error'.
+func (r *Request) RestrictRedirects(sameHost bool, allowDowngrade bool) *Request {
+	r.checkRedirect = func(request *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
 		}
-	}
 
-	if r.preRequestFunc != nil {
-		preRequestResult := r.preRequestFunc()
-		if preRequestResult.Type != Success {
-			return AnnotateResult(preRequestResult, "received non successful result from pre request func")
+		previous := via[len(via)-1]
+		if sameHost && request.URL.Host != via[0].URL.Host {
+			return fmt.Errorf("%w: redirect from host %s to %s", ErrRedirectNotAllowed, via[0].URL.Host, request.URL.Host)
 		}
-	}
 
-	response, err := r.perform()
-	if err != nil {
-		return &Result{
-			Type:        Error,
-			Description: fmt.Sprintf("received an error while performing request: %s", err.Error()),
+		if !allowDowngrade && previous.URL.Scheme == "https" && request.URL.Scheme == "http" {
+			return fmt.Errorf("%w: redirect from https to http", ErrRedirectNotAllowed)
 		}
-	}
 
-	err = r.readBody(response)
-	if err != nil {
-		return &Result{
-			Type:        Error,
-			Description: fmt.Sprintf("received an error while reading body: %s", err.Error()),
-		}
+		return nil
 	}
+	return r
+}
 
-	result := Result{
-		Type:           Success,
-		DownStreamArgs: map[string]string{},
-	}
-	if len(r.assertions) == 0 {
-		result = Result{
-			Type:           NoTest,
-			DownStreamArgs: map[string]string{},
-		}
-	}
+// Set a custom round tripper used to send the request, e.g. for mocking, instrumentation or record and replay.
+// The timeout and redirect restrictions still apply, while TLSConfig, UnixSocket and the transport level timeouts are ignored.
+func (r *Request) Transport(rt http.RoundTripper) *Request {
+	r.transport = rt
+	return r
+}
 
-	assertResult := r.checkAssertions(response)
-	if assertResult.Type != Success {
-		return AnnotateResult(assertResult, "assertion failed")
-	}
+// Send the request over the Unix domain socket at the path, regardless of the host of the url which becomes a placeholder,
+// e.g. http://localhost/containers/json.
+func (r *Request) UnixSocket(path string) *Request {
+	r.unixSocket = path
+	return r
+}
 
-	if r.testFunc != nil {
-		result = r.testFunc(response, args...)
-		if result.Type == Repeat {
-			r.iterations--
-			if r.iterations == 0 {
-				return &Result{
-					Type:        Failure,
-					Description: "failed after running out of iterations",
-				}
-			}
+// Send the request using HTTP/1.0 instead of HTTP/1.1 or HTTP/2, over a new connection which is closed after the response,
+// e.g. for testing legacy clients and proxies. Ignored when a custom Transport is set.
+func (r *Request) HTTP10() *Request {
+	r.http10 = true
+	return r
+}
 
-			time.Sleep(r.sleep)
+// Set the timeout for establishing the connection, separate from the timeout of the whole request. A connection not established
+// within the timeout results in a 'Timeout'.
+func (r *Request) DialTimeout(d time.Duration) *Request {
+	r.dialTimeout = d
+	return r
+}
 
-			return r.Run(result.DownStreamArgs)
+// Set the timeout for waiting on the response headers once the request is written, separate from the timeout of the whole request.
+func (r *Request) ResponseHeaderTimeout(d time.Duration) *Request {
+	r.headerTimeout = d
+	return r
+}
+
+// Set the maximum inactivity of the connection, renewed on each read and write. Unlike the timeout of the whole request
+// it allows slow but steady responses, while failing on a server stalling mid-body.
+func (r *Request) ConnectionDeadline(d time.Duration) *Request {
+	r.connDeadline = d
+	return r
+}
+
+// A connection setting a deadline before each read and write.
+type deadlineConn struct {
+	net.Conn
+	deadline time.Duration
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.deadline)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(p)
+}
+
+func (c *deadlineConn) Write(p []byte) (int, error) {
+	if err := c.Conn.SetWriteDeadline(time.Now().Add(c.deadline)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(p)
+}
+
+// Reports whether any option requiring a transport of its own is set.
+func (r *Request) hasTransportOptions() bool {
+	return r.tlsConfig != nil || r.unixSocket != "" || r.dialTimeout > 0 || r.headerTimeout > 0 || r.connDeadline > 0 || r.http10
+}
+
+func (r *Request) client() *http.Client {
+	c := &http.Client{
+		Timeout:       time.Duration(r.timeout) * time.Second,
+		Transport:     r.transport,
+		CheckRedirect: r.checkRedirect,
+	}
+
+	if r.transport == nil && r.hasTransportOptions() {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = r.tlsConfig
+		transport.ResponseHeaderTimeout = r.headerTimeout
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}
+		if r.dialTimeout > 0 {
+			dialer.Timeout = r.dialTimeout
+		}
+		transport.DialContext = dialer.DialContext
+		if r.unixSocket != "" {
+			transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", r.unixSocket)
+			}
+		}
+		if r.connDeadline > 0 {
+			dial := transport.DialContext
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := dial(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				return &deadlineConn{Conn: conn, deadline: r.connDeadline}, nil
+			}
+		}
+		c.Transport = transport
+		if r.http10 {
+			c.Transport = &http10Transport{dial: transport.DialContext, tlsConfig: r.tlsConfig}
+		}
+	}
+
+	if r.faults != nil {
+		r.faults.next = c.Transport
+		if r.faults.next == nil {
+			r.faults.next = http.DefaultTransport
+		}
+		c.Transport = r.faults
+	}
+
+	return c
+}
+
+func (r *Request) perform() (*http.Response, error) {
+	return r.performWith(nil)
+}
+
+// Performs an additional request on behalf of an assertion, with the headers added to those of the request. The trace, latency
+// and dump state of the response asserted on is preserved, while the call is still counted. Returns the latency of the request.
+func (r *Request) performExtra(header http.Header) (*http.Response, time.Duration, error) {
+	timeToFirstByte, connReused, latencies, requestDump := r.timeToFirstByte, r.connReused, r.latencies, r.requestDump
+	defer func() {
+		r.timeToFirstByte, r.connReused, r.latencies, r.requestDump = timeToFirstByte, connReused, latencies, requestDump
+	}()
+
+	r.latencies = nil
+	r.extraCalls++
+	response, err := r.performWith(header)
+
+	var latency time.Duration
+	if len(r.latencies) > 0 {
+		latency = r.latencies[0]
+	}
+	return response, latency, err
+}
+
+// Returns the number of requests sent during the run, including those performed by assertions.
+func (r *Request) networkCalls() int {
+	return len(r.latencies) + r.extraCalls
+}
+
+func (r *Request) performWith(header http.Header) (*http.Response, error) {
+	body := r.body
+	if r.bodyFunc != nil {
+		body = r.bodyFunc(r.attempt)
+	}
+
+	if r.bodyErr != nil {
+		return nil, r.bodyErr
+	}
+
+	var reader io.Reader
+	if body != nil {
+		body = []byte(r.expand(string(body)))
+		if r.gzipBody {
+			var err error
+			body, err = gzipCompress(body)
+			if err != nil {
+				return nil, err
+			}
+		}
+		reader = bytes.NewReader(body)
+	}
+
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	request, err := http.NewRequestWithContext(ctx, r.method, r.expand(r.url), reader)
+	if err != nil {
+		return nil, err
+	}
+	if r.rawPath != "" {
+		request.URL.Opaque = r.rawPath
+		if strings.HasPrefix(r.rawPath, "//") {
+			request.URL.Opaque = "//" + request.URL.Host + r.rawPath
+		}
+	}
+	request.Header = r.expandHeaders()
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	if r.http10 {
+		request.Proto, request.ProtoMajor, request.ProtoMinor = "HTTP/1.0", 1, 0
+		request.Close = true
+	}
+	if r.gzipBody && body != nil {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+	if r.idempotency != nil {
+		request.Header.Set(r.idempotency.header, r.idempotency.keyFor(body))
+	}
+	if host := request.Header.Get("Host"); host != "" {
+		request.Host = host
+	}
+	if r.modifyRequest != nil {
+		r.modifyRequest(request)
+	}
+	if r.awsCredentials != nil {
+		r.awsCredentials.sign(request, body, time.Now())
+	}
+	if r.dumpWriter != nil {
+		r.requestDump, _ = httputil.DumpRequestOut(request, true)
+	}
+
+	start := time.Now()
+	r.timeToFirstByte = 0
+	r.connReused = false
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.connReused = info.Reused
+		},
+		GotFirstResponseByte: func() {
+			r.timeToFirstByte = time.Since(start)
+		},
+	}
+	request = request.WithContext(httptrace.WithClientTrace(request.Context(), trace))
+
+	response, err := r.client().Do(request)
+	r.latencies = append(r.latencies, time.Since(start))
+
+	return response, err
+}
+
+// Set a function modifying the constructed request right before it is sent, and signed if SignAWSV4 is set.
+func (r *Request) ModifyRequest(fn func(request *http.Request)) *Request {
+	r.modifyRequest = fn
+	return r
+}
+
+// Set a function modifying the received response before the body is read and any assertions or test function are run.
+// The body is unread, a function consuming it must replace it for the body to be available to assertions.
+func (r *Request) ModifyResponse(fn func(response *http.Response)) *Request {
+	r.modifyResponse = fn
+	return r
+}
+
+// Skip reading the response body, which is closed unread. Only useful when asserting on status and headers, body assertions will result in an 'Error'.
+func (r *Request) SkipBodyRead() *Request {
+	r.skipBodyRead = true
+	return r
+}
+
+// Write the full request and response to the writer when the result is a 'Failure', an 'Error' or a 'Timeout', keeping successful runs quiet.
+// Only the last attempt is written.
+func (r *Request) DumpOnFailure(w io.Writer) *Request {
+	r.dumpWriter = w
+	return r
+}
+
+// Set the maximum size in bytes of the response body read, as received before any decompression.
+// A larger body results in an 'Error'.
+func (r *Request) MaxBodySize(n int64) *Request {
+	r.maxBodySize = n
+	return r
+}
+
+// A reader failing with ErrBodyTooLarge when more than the remaining bytes are read.
+type maxBodyReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (m *maxBodyReader) Read(p []byte) (int, error) {
+	if m.remaining <= 0 {
+		var probe [1]byte
+		n, err := m.reader.Read(probe[:])
+		if n > 0 {
+			return 0, ErrBodyTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > m.remaining {
+		p = p[:m.remaining]
+	}
+	n, err := m.reader.Read(p)
+	m.remaining -= int64(n)
+	return n, err
+}
+
+// Normalize a json response body into a canonical form, compact and with sorted object keys, before any assertions.
+// A body which is not json is left as is.
+func (r *Request) NormalizeJson() *Request {
+	r.normalizeJson = true
+	return r
+}
+
+// Returns a reader decompressing a body of the content encoding gzip, deflate or br. Other encodings are read as is.
+// Brotli is not supported by the standard library and is decoded using github.com/andybalholm/brotli.
+func decompressReader(encoding string, body io.Reader) (io.Reader, error) {
+	var reader io.Reader
+	var err error
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(body)
+	case "deflate":
+		reader, err = zlib.NewReader(body)
+	case "br":
+		reader = brotli.NewReader(body)
+	default:
+		return body, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s body: %w", encoding, err)
+	}
+	return reader, nil
+}
+
+// Decompresses a body of the content encoding gzip, deflate or br. Other encodings are returned as is.
+func decompress(encoding string, b []byte) ([]byte, error) {
+	reader, err := decompressReader(encoding, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s body: %w", encoding, err)
+	}
+	return decompressed, nil
+}
+
+// Reads the response body, decompressing it according to the Content-Encoding header which is left as is.
+func (r *Request) readBody(response *http.Response) error {
+	if response.Body == nil {
+		response.Body = http.NoBody
+	}
+	defer response.Body.Close()
+
+	r.rawBody = nil
+	if r.skipBodyRead {
+		r.responseBody = nil
+		return nil
+	}
+
+	var body io.Reader = response.Body
+	if r.maxBodySize > 0 {
+		body = &maxBodyReader{
+			reader:    response.Body,
+			remaining: r.maxBodySize,
+		}
+	}
+
+	if slices.ContainsFunc(r.assertions, func(a *assertion) bool { return a.streamsBody }) {
+		reader, err := decompressReader(response.Header.Get("Content-Encoding"), body)
+		if err != nil {
+			return err
+		}
+
+		r.responseBody = nil
+		r.streamJsonErr = validateJsonStream(reader)
+		if errors.Is(r.streamJsonErr, ErrBodyTooLarge) {
+			return r.streamJsonErr
+		}
+		response.Body = http.NoBody
+		return nil
+	}
+
+	raw, b, err := r.decodeBody(response, body)
+	if err != nil {
+		return err
+	}
+
+	response.Body = io.NopCloser(bytes.NewReader(raw))
+	r.rawBody = raw
+	r.responseBody = b
+
+	return nil
+}
+
+// Reads the whole body, decompressing it according to the Content-Encoding of the response. Returns the decompressed body,
+// and the body to assert on which is also normalized if NormalizeJson is set.
+func (r *Request) decodeBody(response *http.Response, body io.Reader) ([]byte, []byte, error) {
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, err = decompress(response.Header.Get("Content-Encoding"), raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := raw
+	if r.normalizeJson {
+		if normalized, err := normalizeJson(raw); err == nil {
+			b = normalized
+		}
+	}
+	return raw, b, nil
+}
+
+// Reads and closes the body of a response received by an additional request, decoded like the body of the request.
+func (r *Request) readExtraBody(response *http.Response) ([]byte, error) {
+	if response.Body == nil {
+		return nil, nil
+	}
+	defer response.Body.Close()
+
+	var body io.Reader = response.Body
+	if r.maxBodySize > 0 {
+		body = &maxBodyReader{
+			reader:    response.Body,
+			remaining: r.maxBodySize,
+		}
+	}
+
+	_, b, err := r.decodeBody(response, body)
+	return b, err
+}
+
+// Returns the variables of the current run, shared between the pre-request, test and post-request functions.
+// The variables are reset at the start of each run and preserved across repeated iterations.
+func (r *Request) Vars() map[string]any {
+	if r.vars == nil {
+		r.vars = map[string]any{}
+	}
+	return r.vars
+}
+
+// Performs the request, any pre-request/post-request functions, the test and assertions.
+func (r *Request) Run(args ...any) *Result {
+	r.vars = map[string]any{}
+	r.attempt = 0
+	if r.seeded {
+		r.random = rand.New(rand.NewSource(r.seed))
+	}
+	r.statusCode = 0
+	r.responseBody = nil
+	r.latencies = nil
+	r.extraCalls = 0
+	r.requestDump = nil
+	r.responseDump = nil
+	r.streamJsonErr = nil
+	if r.idempotency != nil {
+		r.idempotency.key = ""
+	}
+
+	start := time.Now()
+	result := r.run(args...)
+	result.Metrics = &Metrics{
+		Attempts:      r.attempt,
+		BytesReceived: len(r.responseBody),
+		StatusCode:    r.statusCode,
+		NetworkCalls:  r.networkCalls(),
+		Duration:      time.Since(start),
+	}
+	result.Warnings = r.warnings
+
+	if r.dumpWriter != nil && (result.Type == Failure || result.Type == Error || result.Type == Timeout) {
+		fmt.Fprintf(r.dumpWriter, "request %s resulted in %s: %s\n%s\n\n%s\n", r.id, result.Type, result.Description, r.requestDump, r.responseDump)
+	}
+
+	if r.resultMapper != nil {
+		metrics, warnings := result.Metrics, result.Warnings
+		if mapped := r.resultMapper(result); mapped != nil {
+			result = mapped
+		}
+		if result.Metrics == nil {
+			result.Metrics = metrics
+		}
+		if result.Warnings == nil {
+			result.Warnings = warnings
+		}
+	}
+
+	return result
+}
+
+// Performs the request like Run, reporting a result of type 'Failure' using t.Errorf, 'Error' or 'Timeout' using t.Fatalf and 'Skip' using t.Skipf.
+func (r *Request) RunT(t testing.TB, args ...any) *Result {
+	t.Helper()
+
+	result := r.Run(args...)
+	switch result.Type {
+	case Failure:
+		t.Errorf("request %s failed: %s", r.id, result.Description)
+	case Error, Timeout:
+		t.Fatalf("request %s received an error: %s", r.id, result.Description)
+	case Skip:
+		t.Skipf("request %s skipped: %s", r.id, result.Description)
+	}
+
+	return result
+}
+
+// A result wrapper for fluent assertions in go tests.
+type RunResult struct {
+	result *Result
+}
+
+// Performs the request like Run, wrapping the result for fluent assertions.
+func (r *Request) RunResult(args ...any) *RunResult {
+	return &RunResult{
+		result: r.Run(args...),
+	}
+}
+
+// Fails the test using t.Fatalf unless the result type is 'Success'.
+func (rr *RunResult) MustSucceed(t testing.TB) *RunResult {
+	t.Helper()
+
+	if rr.result.Type != Success {
+		t.Fatalf("expected success but received %s: %s", rr.result.Type, rr.result.Description)
+	}
+	return rr
+}
+
+// Returns the type of the result.
+func (rr *RunResult) Type() ResultType {
+	return rr.result.Type
+}
+
+// Returns true if the downstream args of the result contain the key.
+func (rr *RunResult) ArgsContain(key string) bool {
+	_, ok := rr.result.DownStreamArgs[key]
+	return ok
+}
+
+// Returns the wrapped result.
+func (rr *RunResult) Result() *Result {
+	return rr.result
+}
+
+// Returns Timeout for errors caused by a timeout or an exceeded deadline, otherwise Error.
+func errorType(err error) ResultType {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return Timeout
+	}
+	return Error
+}
+
+// Performs attempts of the request until an attempt is not to be repeated or the iterations are exhausted.
+func (r *Request) run(args ...any) *Result {
+	var retries int
+	for {
+		r.attempt++
+		r.warnings = nil
+		r.retried = false
+		r.incomingArgs = incomingArgs(r.upstreamArgs, args)
+		result, next := r.runAttempt(args...)
+		if r.onAttempt != nil {
+			r.onAttempt(r.attempt, result)
+		}
+
+		if result.Type != Repeat {
+			return result
+		}
+
+		if r.attempt >= r.iterations {
+			return &Result{
+				Type:        Failure,
+				Description: "failed after running out of iterations",
+			}
+		}
+
+		sleep := r.sleep
+		if r.retried && r.backoffBase > 0 {
+			retries++
+			sleep = r.backoff(retries)
+		} else {
+			retries = 0
+		}
+		time.Sleep(sleep)
+		args = next
+	}
+}
+
+// Performs a single attempt of the request. If the attempt is to be repeated the args for the next attempt are returned.
+func (r *Request) runAttempt(args ...any) (*Result, []any) {
+	r.extracted = map[string]string{}
+
+	if r.url == "" {
+		return &Result{
+			Type:        Error,
+			Description: ErrURLRequired.Error(),
+			Err:         ErrURLRequired,
+		}, nil
+	} else if r.method == "" {
+		return &Result{
+			Type:        Error,
+			Description: ErrMethodRequired.Error(),
+			Err:         ErrMethodRequired,
+		}, nil
+	}
+
+	if r.preRequestFunc != nil {
+		preRequestResult := r.preRequestFunc()
+		if preRequestResult.Type != Success {
+			return AnnotateResult(preRequestResult, "received non successful result from pre request func"), nil
+		}
+	}
+
+	response, err := r.perform()
+	if err != nil && r.retryOnError {
+		r.retried = true
+		return &Result{
+			Type:        Repeat,
+			Description: fmt.Sprintf("received an error while performing request: %s", err.Error()),
+			Err:         err,
+		}, args
+	} else if err != nil {
+		return &Result{
+			Type:        errorType(err),
+			Description: fmt.Sprintf("received an error while performing request: %s", err.Error()),
+			Err:         err,
+		}, nil
+	}
+
+	if r.modifyResponse != nil {
+		r.modifyResponse(response)
+	}
+
+	r.statusCode = response.StatusCode
+
+	err = r.readBody(response)
+	if err != nil {
+		return &Result{
+			Type:        errorType(err),
+			Description: fmt.Sprintf("received an error while reading body: %s", err.Error()),
+			Err:         err,
+		}, nil
+	}
+	if r.dumpWriter != nil {
+		r.responseDump, _ = httputil.DumpResponse(response, !r.skipBodyRead)
+	}
+
+	if slices.Contains(r.retryOn, response.StatusCode) {
+		r.retried = true
+		return &Result{
+			Type:        Repeat,
+			Description: fmt.Sprintf("received status code %d, retrying", response.StatusCode),
+		}, args
+	}
+
+	result := Result{
+		Type:           Success,
+		DownStreamArgs: map[string]string{},
+	}
+	if !slices.ContainsFunc(r.assertions, func(a *assertion) bool { return !a.extracts }) {
+		result = Result{
+			Type:           NoTest,
+			DownStreamArgs: map[string]string{},
+		}
+	}
+
+	if !r.assertAfterTest {
+		assertResult := r.checkAssertions(response)
+		if assertResult.Type != Success {
+			return AnnotateResult(assertResult, "assertion failed"), nil
+		}
+	}
+
+	for i, testFunc := range r.testFuncs {
+		if i > 0 && r.rawBody != nil {
+			response.Body = io.NopCloser(bytes.NewReader(r.rawBody))
+		}
+
+		previous := result.DownStreamArgs
+		result = testFunc(response, args...)
+		if result.Type == Repeat {
+			return &result, []any{result.DownStreamArgs}
+		}
+
+		if i > 0 {
+			args := result.DownStreamArgs
+			result.DownStreamArgs = previous
+			mergeArgs(&result, args)
+		}
+		if result.Type != Success {
+			break
+		}
+	}
+
+	if r.assertAfterTest {
+		assertResult := r.checkAssertions(response)
+		if assertResult.Type != Success {
+			return AnnotateResult(assertResult, "assertion failed"), nil
+		}
+	}
+	mergeArgs(&result, r.extracted)
+
+	if r.postRequestFunc != nil {
+		postRequestResult := r.postRequestFunc(&result)
+		if postRequestResult.Type != Success {
+			return AnnotateResult(postRequestResult, "received non successful result from post request func"), nil
+		}
+		mergeArgs(&result, postRequestResult.DownStreamArgs)
+	}
+
+	return &result, nil
+}
+
+// Adds the args to the downstream args of the result, overwriting args with the same key.
+func mergeArgs(result *Result, args map[string]string) {
+	if len(args) > 0 && result.DownStreamArgs == nil {
+		result.DownStreamArgs = map[string]string{}
+	}
+	for k, v := range args {
+		result.DownStreamArgs[k] = v
+	}
+}
+
+// Extract the value of the response header into the downstream arg with the key, e.g. a Location or a rate limit token.
+// A missing header results in a 'Failure'. Extracting is not counted as an assertion, a request only extracting results in 'NoTest'.
+func (r *Request) ExtractHeader(argKey, headerName string) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		values := response.Header.Values(headerName)
+		if len(values) == 0 {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response has no header '%s' to extract", headerName),
+			}
+		}
+
+		r.extracted[argKey] = values[0]
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	r.assertions[len(r.assertions)-1].extracts = true
+	return r
+}
+
+// Set the test function, replacing any test functions set earlier.
+func (r *Request) Test(testFunc func(response *http.Response, args ...any) Result) *Request {
+	r.testFuncs = []func(response *http.Response, args ...any) Result{testFunc}
+	return r
+}
+
+// Add a test function, run after those added earlier with a fresh copy of the response body. The first result which is
+// not a 'Success' is the result of the tests, and the rest are not run. A 'Repeat' from any of them repeats the attempt,
+// running all test functions again. The downstream args of the tests are merged, later ones overwriting earlier ones.
+func (r *Request) AddTest(testFunc func(response *http.Response, args ...any) Result) *Request {
+	r.testFuncs = append(r.testFuncs, testFunc)
+	return r
+}
+
+// Set whether the assertions are checked after the test function instead of before it, default false.
+// When checked after, a test function returning Repeat repeats the request without the assertions being checked for that iteration,
+// and when checked before, failing assertions prevent the test function from running.
+func (r *Request) AssertAfterTest(after bool) *Request {
+	r.assertAfterTest = after
+	return r
+}
+
+// Set the pre-request function.
+func (r *Request) PreRequest(preRequestFunc func() *Result) *Request {
+	r.preRequestFunc = preRequestFunc
+	return r
+}
+
+// Set the post-request function which is only run when the request (and subsequent iterations) are complete.
+// Any downstream args in a successful post-request result are merged into the returned result, overwriting args with the same key.
+func (r *Request) PostRequest(postRequestFunc func(*Result) *Result) *Request {
+	r.postRequestFunc = postRequestFunc
+	return r
+}
+
+// Set a function rewriting the result at the very end of Run, e.g. to treat a known flaky 503 as a 'Skip'.
+// The metrics of the run are kept unless the returned result has its own. Returning nil keeps the original result.
+func (r *Request) ResultMapper(fn func(result *Result) *Result) *Request {
+	r.resultMapper = fn
+	return r
+}
+
+// Set a function called after each attempt with the attempt number, starting at 1, and the result of the attempt,
+// e.g. a 'Repeat' result while polling or retrying.
+func (r *Request) OnAttempt(fn func(attempt int, result *Result)) *Request {
+	r.onAttempt = fn
+	return r
+}
+
+// Assert that the status code of the response is of a certain value. A mismatch in recived and expected results in a 'Failure'.
+func (r *Request) StatusCode(expectedStatusCode int) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if response.StatusCode != expectedStatusCode {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected status code, exepcted %d but received %d", expectedStatusCode, response.StatusCode),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the status line of the response equals the expected exactly, e.g. "HTTP/1.1 404 Not Found".
+// A mismatch in received and expected results in a 'Failure'.
+func (r *Request) RawStatusLine(expected string) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if line := response.Proto + " " + response.Status; line != expected {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected status line, expected '%s' but received '%s'", expected, line),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response is not modified, i.e. the status code is 304. Any other status code results in a 'Failure'.
+func (r *Request) NotModified() *Request {
+	return r.StatusCode(http.StatusNotModified)
+}
+
+// Assert that the response body is empty. A non empty response body results in a 'Failure'.
+func (r *Request) BodyIsEmpty() *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		if r.responseBody == nil {
+			return &Result{
+				Type:        Failure,
+				Description: "received nil response",
+			}
+		}
+
+		if len(r.responseBody) != 0 {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received non empty body, body had length of: %d", len(r.responseBody)),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response body is of a certain value. A mismatch in received and expected results in a 'Failure'.
+// If NormalizeJson is set the expected body is normalized as well.
+func (r *Request) BodyEquals(expected []byte) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		want := expected
+		if r.normalizeJson {
+			if normalized, err := normalizeJson(expected); err == nil {
+				want = normalized
+			}
+		}
+
+		if !bytes.Equal(r.responseBody, want) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected body, expected '%s' but received '%s'", want, r.responseBody),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response body as text equals the expected, ignoring leading and trailing whitespace of both, e.g. a trailing newline.
+// A mismatch in received and expected results in a 'Failure'.
+func (r *Request) BodyTextEquals(expected string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		if received := strings.TrimSpace(string(r.responseBody)); received != strings.TrimSpace(expected) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected body, expected '%s' but received '%s'", strings.TrimSpace(expected), received),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response body contains the substring. A body not containing it results in a 'Failure'.
+func (r *Request) BodyContains(substr string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		if !bytes.Contains(r.responseBody, []byte(substr)) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response body does not contain '%s'", substr),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response body does not contain the substring, e.g. stack traces or secrets.
+// A body containing it results in a 'Failure' including a snippet around the first match.
+func (r *Request) BodyNotContains(substr string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		i := bytes.Index(r.responseBody, []byte(substr))
+		if i >= 0 {
+			start := max(i-20, 0)
+			end := min(i+len(substr)+20, len(r.responseBody))
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response body contains '%s' at offset %d: '%s'", substr, i, r.responseBody[start:end]),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the SHA-256 digest of the response body equals the hex encoded digest, e.g. for verifying static assets.
+// A mismatch results in a 'Failure' including both digests.
+func (r *Request) BodySHA256(hexDigest string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		sum := sha256.Sum256(r.responseBody)
+		digest := hex.EncodeToString(sum[:])
+		if !strings.EqualFold(digest, hexDigest) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected body digest, expected '%s' but received '%s'", hexDigest, digest),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response body is well-formed gzip, e.g. for download endpoints serving archives. A body which is decompressed
+// according to its Content-Encoding is asserted on after decompression. A malformed or truncated body results in a 'Failure'.
+func (r *Request) BodyIsValidGzip() *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		reader, err := gzip.NewReader(bytes.NewReader(r.responseBody))
+		if err == nil {
+			_, err = io.Copy(io.Discard, reader)
+		}
+		if err != nil {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response body is not valid gzip: %s", err.Error()),
+				Err:         err,
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response body is json. A non json response body results in a 'Failure'.
+func (r *Request) BodyIsJson() *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		if r.responseBody == nil {
+			return &Result{
+				Type:        Failure,
+				Description: "received nil response",
+			}
+		}
+
+		var js json.RawMessage
+		err := json.Unmarshal(r.responseBody, &js)
+		if err != nil {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("failed to unmarshal the response body: '%s'", r.responseBody),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the protocol negotiated using ALPN equals the expected, e.g. h2 or http/1.1. A mismatch results in a 'Failure'.
+// A response not received over TLS results in an 'Error'.
+func (r *Request) NegotiatedProtocol(proto string) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if response.TLS == nil {
+			return &Result{
+				Type:        Error,
+				Description: "response was not received over tls",
+			}
+		}
+
+		if response.TLS.NegotiatedProtocol != proto {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected negotiated protocol, expected '%s' but received '%s'", proto, response.TLS.NegotiatedProtocol),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the leaf certificate of the server does not expire within the given duration. A certificate expiring within the duration results in a 'Failure'.
+// A response not received over TLS results in an 'Error'.
+func (r *Request) CertNotExpiringWithin(d time.Duration) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if response.TLS == nil || len(response.TLS.PeerCertificates) == 0 {
+			return &Result{
+				Type:        Error,
+				Description: "response was not received over tls",
+			}
+		}
+
+		notAfter := response.TLS.PeerCertificates[0].NotAfter
+		if time.Until(notAfter) < d {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("certificate expires at %s, which is within %s", notAfter.Format(time.RFC3339), d),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the url of the final request, after any redirects, is of a certain value. A mismatch in received and expected results in a 'Failure'.
+func (r *Request) FinalURLEquals(expected string) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if response.Request == nil || response.Request.URL == nil {
+			return &Result{
+				Type:        Error,
+				Description: "response has no request url",
+			}
+		}
+
+		if finalURL := response.Request.URL.String(); finalURL != expected {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected final url, expected '%s' but received '%s'", expected, finalURL),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+func (r *Request) addAssertion(check func(r *Request, response *http.Response) *Result) {
+	r.assertions = append(r.assertions, &assertion{check: check})
+}
+
+func (r *Request) addBodyAssertion(check func(r *Request, response *http.Response) *Result) {
+	r.assertions = append(r.assertions, &assertion{readsBody: true, check: check})
+}
+
+// A custom assertion, receiving the response and the read response body.
+type AssertionFunc func(response *http.Response, body []byte) *Result
+
+// Add a custom assertion. A non successful result from the assertion is returned as the result of the request.
+func (r *Request) Assert(fn AssertionFunc) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		return fn(response, r.responseBody)
+	})
+	return r
+}
+
+// Add a custom assertion on the response body, like Assert but resulting in an 'Error' when used with SkipBodyRead.
+func (r *Request) AssertBody(fn AssertionFunc) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		return fn(response, r.responseBody)
+	})
+	return r
+}
+
+// Creates an empty request used only to hold a reusable set of assertions, which are added to other requests using Apply.
+func AssertionSet() *Request {
+	return &Request{
+		headers: http.Header{},
+	}
+}
+
+// Add the assertions of the given sets to the request. Only the assertions are applied, any other configuration of the sets is ignored.
+func (r *Request) Apply(sets ...*Request) *Request {
+	for _, set := range sets {
+		for _, a := range set.assertions {
+			applied := *a
+			r.assertions = append(r.assertions, &applied)
+		}
+	}
+	return r
+}
+
+// Skip the most recently added assertion, instead of failing, when the value it asserts on is missing from the response.
+func (r *Request) SkipIfMissing() *Request {
+	if len(r.assertions) > 0 {
+		r.assertions[len(r.assertions)-1].skipIfMissing = true
+	}
+	return r
+}
+
+// Mark the most recently added assertion as a warning. Instead of failing the request a 'Failure' of the assertion is
+// recorded in the warnings of the result, e.g. for informational checks.
+func (r *Request) AsWarning() *Request {
+	if len(r.assertions) > 0 {
+		r.assertions[len(r.assertions)-1].warning = true
+	}
+	return r
+}
+
+// Set a label on the most recently added assertion, which is included in the description of a failing assertion.
+func (r *Request) Label(label string) *Request {
+	if len(r.assertions) > 0 {
+		r.assertions[len(r.assertions)-1].label = label
+	}
+	return r
+}
+
+// The default headers, and the value they contain, indicating that a response was served from a cache.
+var defaultCacheHeaders = map[string]string{
+	"X-Cache": "HIT",
+}
+
+// Set the headers, and the value they contain, indicating that a response was served from a cache.
+// The value is matched case-insensitively as a substring, default is an X-Cache header containing HIT.
+func (r *Request) CacheHeaders(headers map[string]string) *Request {
+	r.cacheHeaders = headers
+	return r
+}
+
+func (r *Request) servedFromCache(response *http.Response) bool {
+	if age, err := strconv.Atoi(response.Header.Get("Age")); err == nil && age > 0 {
+		return true
+	}
+
+	cacheHeaders := r.cacheHeaders
+	if cacheHeaders == nil {
+		cacheHeaders = defaultCacheHeaders
+	}
+
+	for key, value := range cacheHeaders {
+		for _, v := range response.Header.Values(key) {
+			if strings.Contains(strings.ToLower(v), strings.ToLower(value)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Assert that the response was served from a cache, i.e. has an Age header greater than zero or a cache header. A response served from the origin results in a 'Failure'.
+func (r *Request) ServedFromCache() *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if !r.servedFromCache(response) {
+			return &Result{
+				Type:        Failure,
+				Description: "response was not served from a cache",
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response was served from the origin, the inverse of ServedFromCache. A response served from a cache results in a 'Failure'.
+func (r *Request) ServedFromOrigin() *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if r.servedFromCache(response) {
+			return &Result{
+				Type:        Failure,
+				Description: "response was served from a cache",
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response body is valid UTF-8. An invalid body results in a 'Failure' describing the offset of the first invalid byte.
+func (r *Request) BodyIsValidUTF8() *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		if utf8.Valid(r.responseBody) {
+			return &Result{
+				Type: Success,
+			}
+		}
+
+		offset := 0
+		for offset < len(r.responseBody) {
+			c, size := utf8.DecodeRune(r.responseBody[offset:])
+			if c == utf8.RuneError && size == 1 {
+				break
+			}
+			offset += size
+		}
+
+		return &Result{
+			Type:        Failure,
+			Description: fmt.Sprintf("received invalid utf-8 body, first invalid byte at offset %d", offset),
+		}
+	})
+	return r
+}
+
+// Assert that the number of requests sent during the run, up until the assertion is run, equals n. Retries and repeats are included,
+// use AssertAfterTest for repeats by the test function to be counted. A mismatch results in a 'Failure'.
+func (r *Request) AssertNetworkCalls(n int) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if calls := r.networkCalls(); calls != n {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected number of network calls, expected %d but received %d", n, calls),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response was received within the duration from sending the request, measured for the latest attempt.
+// A slower response results in a 'Failure'.
+func (r *Request) MaxDuration(d time.Duration) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if len(r.latencies) == 0 {
+			return &Result{
+				Type:        Error,
+				Description: "no duration recorded for the request",
+			}
+		}
+
+		latency := r.latencies[len(r.latencies)-1]
+		if latency > d {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received response after %s, expected within %s", latency, d),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the first byte of the response was received within the duration from sending the request. A slower response results in a 'Failure'.
+// A time to first byte which was not measured, e.g. when using a custom Transport, results in an 'Error'.
+func (r *Request) MaxTimeToFirstByte(d time.Duration) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if r.timeToFirstByte == 0 {
+			return &Result{
+				Type:        Error,
+				Description: "no time to first byte recorded for the request",
+				Err:         ErrValueMissing,
+			}
 		}
-	}
 
-	if r.postRequestFunc != nil {
-		postRequestResult := r.postRequestFunc(&result)
-		if postRequestResult.Type != Success {
-			return AnnotateResult(postRequestResult, "received non successful result from post request func")
+		if r.timeToFirstByte > d {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received first byte after %s, expected within %s", r.timeToFirstByte, d),
+			}
 		}
-	}
 
-	return &result
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
 }
 
-// Set the test function.
-func (r *Request) Test(testFunc func(response *http.Response, args ...any) Result) *Request {
-	r.testFunc = testFunc
+// Assert that the request reused a previously opened connection, e.g. from an earlier request in a group sharing connections.
+// A request using a new connection results in a 'Failure'.
+func (r *Request) ConnectionReused() *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if !r.connReused {
+			return &Result{
+				Type:        Failure,
+				Description: "request did not reuse a connection",
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
 	return r
 }
 
-// Set the pre-request function.
-func (r *Request) PreRequest(preRequestFunc func() *Result) *Request {
-	r.preRequestFunc = preRequestFunc
+// Assert that the request is idempotent by performing it a second time and comparing the status code and body of the responses.
+// Only meaningful for safe or idempotent methods such as GET, PUT and DELETE. Differing responses results in a 'Failure'.
+func (r *Request) AssertIdempotent() *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		second, _, err := r.performExtra(nil)
+		if err != nil {
+			return &Result{
+				Type:        Error,
+				Description: fmt.Sprintf("received an error while performing second request: %s", err.Error()),
+				Err:         err,
+			}
+		}
+
+		body, err := r.readExtraBody(second)
+		if err != nil {
+			return &Result{
+				Type:        Error,
+				Description: fmt.Sprintf("received an error while reading second body: %s", err.Error()),
+				Err:         err,
+			}
+		}
+
+		if second.StatusCode != response.StatusCode {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("request is not idempotent, received status code %d and then %d", response.StatusCode, second.StatusCode),
+			}
+		}
+
+		if !bytes.Equal(body, r.responseBody) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("request is not idempotent, received body '%s' and then '%s'", r.responseBody, body),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
 	return r
 }
 
-// Set the post-request function which is only run when the request (and subsequent iterations) are complete.
-func (r *Request) PostRequest(postRequestFunc func(*Result) *Result) *Request {
-	r.postRequestFunc = postRequestFunc
+// Assert that the response is cacheable by performing a conditional request using its ETag, or Last-Modified if it has no ETag,
+// and asserting that it results in a 304. A response without either, or a conditional request not resulting in a 304, results in a 'Failure'.
+func (r *Request) AssertCacheable() *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		header := http.Header{}
+		if etag := response.Header.Get("ETag"); etag != "" {
+			header.Set("If-None-Match", etag)
+		} else if lastModified := response.Header.Get("Last-Modified"); lastModified != "" {
+			header.Set("If-Modified-Since", lastModified)
+		} else {
+			return &Result{
+				Type:        Failure,
+				Description: "response is not cacheable, it has neither an ETag nor a Last-Modified header",
+			}
+		}
+
+		// Drain and close the first response, so its connection is released for the conditional request, keeping the read body
+		// for any later tests.
+		if response.Body != nil {
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+			response.Body = io.NopCloser(bytes.NewReader(r.rawBody))
+		}
+
+		conditional, _, err := r.performExtra(header)
+		if err != nil {
+			return &Result{
+				Type:        Error,
+				Description: fmt.Sprintf("received an error while performing conditional request: %s", err.Error()),
+				Err:         err,
+			}
+		}
+		if conditional.Body != nil {
+			conditional.Body.Close()
+		}
+
+		if conditional.StatusCode != http.StatusNotModified {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response is not cacheable, conditional request received status code %d", conditional.StatusCode),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
 	return r
 }
 
-// Assert that the status code of the response is of a certain value. A mismatch in recived and expected results in a 'Failure'.
-func (r *Request) StatusCode(expectedStatusCode int) *Request {
-	r.assertions = append(r.assertions, func(response *http.Response) *Result {
-		if response.StatusCode != expectedStatusCode {
+// Assert that the latency of the request is stable, i.e. that the coefficient of variation (standard deviation divided by mean)
+// of the latencies of the samples does not exceed maxCV. The response asserted on is the first sample, additional requests are performed
+// until the number of samples is reached. Too jittery latencies results in a 'Failure', fewer than one sample results in an 'Error'.
+func (r *Request) LatencyStable(samples int, maxCV float64) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if samples < 1 {
+			return &Result{
+				Type:        Error,
+				Description: fmt.Sprintf("invalid number of samples %d, expected at least 1", samples),
+			}
+		}
+
+		var latencies []time.Duration
+		if len(r.latencies) > 0 {
+			latencies = append(latencies, r.latencies[len(r.latencies)-1])
+		}
+		for len(latencies) < samples {
+			sample, latency, err := r.performExtra(nil)
+			if err != nil {
+				return &Result{
+					Type:        Error,
+					Description: fmt.Sprintf("received an error while performing sample request: %s", err.Error()),
+					Err:         err,
+				}
+			}
+			if sample.Body != nil {
+				io.Copy(io.Discard, sample.Body)
+				sample.Body.Close()
+			}
+			latencies = append(latencies, latency)
+		}
+
+		cv := coefficientOfVariation(latencies)
+		if cv > maxCV {
 			return &Result{
 				Type:        Failure,
-				Description: fmt.Sprintf("received unexpected status code, exepcted %d but received %d", expectedStatusCode, response.StatusCode),
+				Description: fmt.Sprintf("latency coefficient of variation %.3f exceeds %.3f, latencies %v", cv, maxCV, latencies),
 			}
 		}
 
@@ -308,20 +2301,70 @@ func (r *Request) StatusCode(expectedStatusCode int) *Request {
 	return r
 }
 
-// Assert that the response body is empty. A non empty response body results in a 'Failure'.
-func (r *Request) BodyIsEmpty() *Request {
-	r.assertions = append(r.assertions, func(response *http.Response) *Result {
-		if r.responseBody == nil {
+func coefficientOfVariation(durations []time.Duration) float64 {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, d := range durations {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(durations))
+	if mean == 0 {
+		return 0
+	}
+
+	var variance float64
+	for _, d := range durations {
+		variance += math.Pow(float64(d)-mean, 2)
+	}
+	variance /= float64(len(durations))
+
+	return math.Sqrt(variance) / mean
+}
+
+// Assert that the response used chunked transfer encoding. A response not using it results in a 'Failure'.
+// The client removes the chunking and the Transfer-Encoding header, but keeps the encodings in response.TransferEncoding
+// which is what is checked. The response content length is then unknown (-1).
+func (r *Request) IsChunked() *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if !slices.Contains(response.TransferEncoding, "chunked") {
 			return &Result{
 				Type:        Failure,
-				Description: "received nil response",
+				Description: fmt.Sprintf("response was not chunked, received transfer encoding %v", response.TransferEncoding),
 			}
 		}
 
-		if len(r.responseBody) != 0 {
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Returns true if the response was compressed, either as indicated by the Content-Encoding header or decompressed transparently by the transport.
+func isCompressed(response *http.Response) bool {
+	if response.Uncompressed {
+		return true
+	}
+
+	for _, encoding := range strings.Split(response.Header.Get("Content-Encoding"), ",") {
+		switch strings.ToLower(strings.TrimSpace(encoding)) {
+		case "gzip", "x-gzip", "deflate", "br":
+			return true
+		}
+	}
+	return false
+}
+
+// Assert that the response is compressed using gzip, deflate or br. An uncompressed response results in a 'Failure'.
+func (r *Request) IsCompressed() *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if !isCompressed(response) {
 			return &Result{
 				Type:        Failure,
-				Description: fmt.Sprintf("received non empty body, body had length of: %d", len(r.responseBody)),
+				Description: fmt.Sprintf("response is not compressed, received content encoding '%s'", response.Header.Get("Content-Encoding")),
 			}
 		}
 
@@ -332,22 +2375,145 @@ func (r *Request) BodyIsEmpty() *Request {
 	return r
 }
 
-// Assert that the response body is json. A non json response body results in a 'Failure'.
-func (r *Request) BodyIsJson() *Request {
-	r.assertions = append(r.assertions, func(response *http.Response) *Result {
-		if r.responseBody == nil {
+// Assert that the response is not compressed. A response compressed using gzip, deflate or br results in a 'Failure'.
+func (r *Request) IsNotCompressed() *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if isCompressed(response) {
 			return &Result{
 				Type:        Failure,
-				Description: "received nil response",
+				Description: fmt.Sprintf("response is compressed, received content encoding '%s'", response.Header.Get("Content-Encoding")),
 			}
 		}
 
-		var js json.RawMessage
-		err := json.Unmarshal(r.responseBody, &js)
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the Date header differs from the local time by at most the duration, e.g. to detect clock skew.
+// A larger difference, or a malformed Date, results in a 'Failure', as does a missing Date unless SkipIfMissing is set.
+func (r *Request) DateWithin(d time.Duration) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		header := response.Header.Get("Date")
+		if header == "" {
+			return &Result{
+				Type:        Failure,
+				Description: "response has no Date header",
+				Err:         ErrValueMissing,
+			}
+		}
+
+		date, err := http.ParseTime(header)
 		if err != nil {
 			return &Result{
 				Type:        Failure,
-				Description: fmt.Sprintf("failed to unmarshal the response body: '%s'", r.responseBody),
+				Description: fmt.Sprintf("failed to parse the Date header '%s'", header),
+				Err:         err,
+			}
+		}
+
+		if skew := time.Since(date).Abs(); skew > d {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received Date '%s' differing from local time by %s, expected within %s", header, skew.Round(time.Second), d),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the media type of the Content-Type header is of a certain value, compared case-insensitively and ignoring any parameters.
+// A mismatch in received and expected, or a missing or malformed Content-Type, results in a 'Failure'.
+func (r *Request) ContentType(expected string) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+		if err != nil {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response has no valid content type, received '%s'", response.Header.Get("Content-Type")),
+				Err:         err,
+			}
+		}
+
+		if !strings.EqualFold(mediaType, expected) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected content type, expected '%s' but received '%s'", expected, mediaType),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the content type sniffed from the response body, using http.DetectContentType, equals the expected, e.g. image/png.
+// Any parameters of the sniffed type are ignored. A mismatch, e.g. mislabeled content, results in a 'Failure'.
+func (r *Request) ContentSniffEquals(expected string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		sniffed := http.DetectContentType(r.responseBody)
+		mediaType, _, err := mime.ParseMediaType(sniffed)
+		if err != nil {
+			mediaType = sniffed
+		}
+
+		if !strings.EqualFold(mediaType, expected) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected sniffed content type, expected '%s' but received '%s'", expected, sniffed),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the charset parameter of the Content-Type header is of a certain value, compared case-insensitively.
+// A mismatch in received and expected results in a 'Failure', as does a missing charset unless SkipIfMissing is set.
+func (r *Request) Charset(expected string) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		_, params, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+		charset, ok := params["charset"]
+		if err != nil || !ok {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response has no charset, received content type '%s'", response.Header.Get("Content-Type")),
+				Err:         ErrValueMissing,
+			}
+		}
+
+		if !strings.EqualFold(charset, expected) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected charset, expected '%s' but received '%s'", expected, charset),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response header is of a certain value. A mismatch in received and expected results in a 'Failure'.
+func (r *Request) HeaderEquals(key, expected string) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if value := response.Header.Get(key); value != expected {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected value of header '%s', expected '%s' but received '%s'", key, expected, value),
 			}
 		}
 
@@ -358,10 +2524,112 @@ func (r *Request) BodyIsJson() *Request {
 	return r
 }
 
+// Set the headers of a CORS preflight request for the origin and method, and assert that the response allows them.
+// A response not allowing the origin, either explicitly or by '*', or not allowing the method results in a 'Failure'.
+func (r *Request) CORSPreflight(origin, method string) *Request {
+	r.headers.Set("Origin", origin)
+	r.headers.Set("Access-Control-Request-Method", method)
+
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		allowedOrigin := response.Header.Get("Access-Control-Allow-Origin")
+		if allowedOrigin != origin && allowedOrigin != "*" {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("origin '%s' is not allowed, received allowed origin '%s'", origin, allowedOrigin),
+			}
+		}
+
+		allowedMethods := response.Header.Get("Access-Control-Allow-Methods")
+		for _, m := range strings.Split(allowedMethods, ",") {
+			if strings.EqualFold(strings.TrimSpace(m), method) {
+				return &Result{
+					Type: Success,
+				}
+			}
+		}
+
+		return &Result{
+			Type:        Failure,
+			Description: fmt.Sprintf("method '%s' is not allowed, received allowed methods '%s'", method, allowedMethods),
+		}
+	})
+	return r
+}
+
+// Returns the response of the first redirect followed to reach the response, nil if it was not redirected.
+func initialRedirect(response *http.Response) *http.Response {
+	var initial *http.Response
+	for response.Request != nil && response.Request.Response != nil {
+		response = response.Request.Response
+		initial = response
+	}
+	return initial
+}
+
+// Assert on the initial redirect response, e.g. its status and Location, using the assertions of the set, while the other
+// assertions apply to the final response as usual. The body of the redirect response is not available to the assertions,
+// body assertions result in an 'Error'. A response which was not redirected results in a 'Failure'.
+func (r *Request) FollowAndAssert(redirect *Request) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		initial := initialRedirect(response)
+		if initial == nil {
+			return &Result{
+				Type:        Failure,
+				Description: "response was not redirected",
+			}
+		}
+
+		for _, a := range redirect.assertions {
+			if a.readsBody {
+				return &Result{
+					Type:        Error,
+					Description: "body assertion used on the redirect response",
+				}
+			}
+		}
+
+		result := r.checkAssertionList(redirect.assertions, initial)
+		if result.Type != Success {
+			return AnnotateResult(result, "redirect response")
+		}
+		return result
+	})
+	return r
+}
+
 func (r *Request) checkAssertions(response *http.Response) *Result {
-	for _, assertion := range r.assertions {
-		result := assertion(response)
+	if response == nil {
+		return &Result{
+			Type:        Error,
+			Description: "received nil response",
+		}
+	}
+
+	return r.checkAssertionList(r.assertions, response)
+}
+
+func (r *Request) checkAssertionList(assertions []*assertion, response *http.Response) *Result {
+	for _, a := range assertions {
+		if a.readsBody && r.skipBodyRead {
+			return &Result{
+				Type:        Error,
+				Description: "body assertion used while skipping body read",
+			}
+		}
+
+		result := a.check(r, response)
+		if a.skipIfMissing && errors.Is(result.Err, ErrValueMissing) {
+			continue
+		}
+
 		if result.Type != Success {
+			if a.label != "" {
+				result = AnnotateResult(result, a.label)
+			}
+			if a.warning && result.Type == Failure {
+				r.warnings = append(r.warnings, result.Description)
+				continue
+			}
 			return result
 		}
 	}
@@ -0,0 +1,62 @@
+package jobbigt
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Listens on a loopback port which never accepts, with the backlog filled, so that any further dial hangs until it times out.
+func listenFullBacklog(t *testing.T) string {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		syscall.Close(fd)
+	})
+
+	err = syscall.Bind(fd, &syscall.SockaddrInet4{Addr: [4]byte{127, 0, 0, 1}})
+	if err == nil {
+		err = syscall.Listen(fd, 0)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := fmt.Sprintf("127.0.0.1:%d", sa.(*syscall.SockaddrInet4).Port)
+
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			return addr
+		}
+		t.Cleanup(func() {
+			conn.Close()
+		})
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	addr := listenFullBacklog(t)
+
+	start := time.Now()
+	result := Get("http://" + addr).
+		Timeout(10).
+		DialTimeout(100 * time.Millisecond).
+		Run()
+
+	if result.Type != Timeout {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the dial timeout to fail fast, took %v", elapsed)
+	}
+}
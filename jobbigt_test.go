@@ -1,10 +1,30 @@
 package jobbigt
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
 )
 
 func isFailure(result *Result, expectedFailure bool) bool {
@@ -210,7 +230,7 @@ func TestUrlRequried(t *testing.T) {
 	request := Request{method: http.MethodGet}
 	result := request.Run()
 
-	if result.Type != Error || result.Description != "url is required" {
+	if result.Type != Error || !errors.Is(result.Err, ErrURLRequired) {
 		t.Errorf("expected error")
 	}
 }
@@ -219,7 +239,7 @@ func TestMethodRequried(t *testing.T) {
 	request := Request{url: "url"}
 	result := request.Run()
 
-	if result.Type != Error || result.Description != "method is required" {
+	if result.Type != Error || !errors.Is(result.Err, ErrMethodRequired) {
 		t.Errorf("expected error")
 	}
 }
@@ -345,3 +365,2837 @@ func TestBasicAuth(t *testing.T) {
 		BasicAuth(username, password).
 		Run()
 }
+
+func TestAddTest(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	}))
+
+	readsBody := func(response *http.Response, args ...any) Result {
+		b, _ := io.ReadAll(response.Body)
+		if string(b) != "body" {
+			return Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected body '%s'", b),
+			}
+		}
+		return Result{
+			Type:           Success,
+			DownStreamArgs: map[string]string{"first": "1"},
+		}
+	}
+
+	var thirdRan bool
+	for id, tc := range []struct {
+		Second          func(response *http.Response, args ...any) Result
+		ExpectedFailure bool
+	}{
+		{
+			Second:          readsBody,
+			ExpectedFailure: false,
+		},
+		{
+			Second: func(response *http.Response, args ...any) Result {
+				return Result{
+					Type: Failure,
+				}
+			},
+			ExpectedFailure: true,
+		},
+	} {
+		thirdRan = false
+		result := Get(testServer.URL).
+			AddTest(readsBody).
+			AddTest(tc.Second).
+			AddTest(func(response *http.Response, args ...any) Result {
+				thirdRan = true
+				return Result{
+					Type:           Success,
+					DownStreamArgs: map[string]string{"third": "3"},
+				}
+			}).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+
+		if thirdRan == tc.ExpectedFailure {
+			t.Errorf("(%d) expected the third test to run only if the second succeeds", id)
+		}
+
+		if !tc.ExpectedFailure && (result.DownStreamArgs["first"] != "1" || result.DownStreamArgs["third"] != "3") {
+			t.Errorf("(%d) expected args of all tests to be merged, received %v", id, result.DownStreamArgs)
+		}
+	}
+}
+
+func TestResultMapper(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	result := Get(testServer.URL).
+		StatusCode(http.StatusOK).
+		ResultMapper(func(result *Result) *Result {
+			if result.Type == Failure && result.Metrics.StatusCode == http.StatusServiceUnavailable {
+				return &Result{
+					Type:        Skip,
+					Description: "service unavailable",
+				}
+			}
+			return result
+		}).
+		Run()
+
+	if result.Type != Skip {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if result.Metrics == nil || result.Metrics.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected metrics to be kept, received: %v", result.Metrics)
+	}
+}
+
+func TestResultMapperNil(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	result := Get(testServer.URL).
+		StatusCode(http.StatusOK).
+		ResultMapper(func(result *Result) *Result {
+			return nil
+		}).
+		Run()
+
+	if result.Type != Failure || result.Metrics == nil {
+		t.Errorf("expected the original result to be kept, received: %v", *result)
+	}
+}
+
+func TestOnAttempt(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var attempts []int
+	var types []ResultType
+	result := Get(testServer.URL).
+		Iterations(3).
+		OnAttempt(func(attempt int, result *Result) {
+			attempts = append(attempts, attempt)
+			types = append(types, result.Type)
+		}).
+		Test(func(response *http.Response, args ...any) Result {
+			return Result{
+				Type: Repeat,
+			}
+		}).
+		Run()
+
+	if result.Type != Failure {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if !slices.Equal(attempts, []int{1, 2, 3}) {
+		t.Errorf("received unexpected attempts: %v", attempts)
+	}
+
+	if !slices.Equal(types, []ResultType{Repeat, Repeat, Repeat}) {
+		t.Errorf("received unexpected attempt results: %v", types)
+	}
+}
+
+func TestWaitForURL(t *testing.T) {
+	ready := time.Now().Add(300 * time.Millisecond)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if time.Now().Before(ready) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	result := WaitForURL(testServer.URL, 2*time.Second)
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+	if result.Metrics.Attempts < 2 {
+		t.Errorf("expected multiple attempts, received %d", result.Metrics.Attempts)
+	}
+
+	testServer.Close()
+	result = WaitForURL(testServer.URL, 300*time.Millisecond)
+	if result.Type != Failure {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestWaitForURLHangingServer(t *testing.T) {
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer testServer.Close()
+	defer close(release)
+
+	start := time.Now()
+	result := WaitForURL(testServer.URL, 300*time.Millisecond)
+	if result.Type != Failure {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if elapsed := time.Since(start); elapsed > 600*time.Millisecond {
+		t.Errorf("expected to give up after about 300ms, took %v", elapsed)
+	}
+}
+
+func TestRetryOn(t *testing.T) {
+	for id, tc := range []struct {
+		Iterations      int
+		ExpectedFailure bool
+	}{
+		{
+			Iterations:      3,
+			ExpectedFailure: false,
+		},
+		{
+			Iterations:      2,
+			ExpectedFailure: true,
+		},
+	} {
+		var attempts int
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			} else {
+				w.WriteHeader(http.StatusOK)
+			}
+		}))
+
+		result := Get(testServer.URL).
+			Iterations(tc.Iterations).
+			RetryOn(http.StatusServiceUnavailable).
+			StatusCode(http.StatusOK).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	var times []time.Time
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		if len(times) <= 4 {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+
+	result := Get(testServer.URL).
+		Iterations(5).
+		RetryOn(http.StatusTooManyRequests).
+		RetryBackoff(20*time.Millisecond, 100*time.Millisecond).
+		StatusCode(http.StatusOK).
+		Run()
+
+	if result.Type != Success {
+		t.Fatalf("received unexpected result: %v", *result)
+	}
+
+	var gaps []time.Duration
+	for i := 1; i < len(times); i++ {
+		gaps = append(gaps, times[i].Sub(times[i-1]))
+	}
+
+	for i, expected := range []time.Duration{20, 40, 80, 100} {
+		if gaps[i] < expected*time.Millisecond {
+			t.Errorf("(%d) received unexpected gap between retries: %v", i, gaps[i])
+		}
+	}
+
+	if !(gaps[0] < gaps[1] && gaps[1] < gaps[2]) {
+		t.Errorf("expected the gaps between retries to grow: %v", gaps)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	r := Get("http://placeholder").RetryBackoff(time.Second, 5*time.Second)
+	for n, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if d := r.backoff(n + 1); d != expected {
+			t.Errorf("(%d) received unexpected backoff: %v", n+1, d)
+		}
+	}
+}
+
+func TestPostRequestDownStreamArgs(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	result := Get(testServer.URL).
+		Test(func(response *http.Response, args ...any) Result {
+			return Result{
+				Type:           Success,
+				DownStreamArgs: map[string]string{"test": "value"},
+			}
+		}).
+		PostRequest(func(r *Result) *Result {
+			return &Result{
+				Type:           Success,
+				DownStreamArgs: map[string]string{"post": "value"},
+			}
+		}).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result type, expected '%d', got '%d'", Success, result.Type)
+	}
+
+	for _, key := range []string{"test", "post"} {
+		if result.DownStreamArgs[key] != "value" {
+			t.Errorf("expected downstream arg '%s' in result: %v", key, result.DownStreamArgs)
+		}
+	}
+}
+
+func newShortLivedTLSServer(t *testing.T, validFor time.Duration) *httptest.Server {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validFor),
+		DNSNames:     []string{"localhost"},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	testServer.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	testServer.StartTLS()
+
+	return testServer
+}
+
+func TestCertNotExpiringWithinAssertion(t *testing.T) {
+	testServer := newShortLivedTLSServer(t, time.Hour)
+
+	for id, tc := range []struct {
+		Within          time.Duration
+		ExpectedFailure bool
+	}{
+		{
+			Within:          time.Minute,
+			ExpectedFailure: false,
+		},
+		{
+			Within:          24 * time.Hour,
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			TLSConfig(&tls.Config{InsecureSkipVerify: true}).
+			CertNotExpiringWithin(tc.Within).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestCertNotExpiringWithinNoTLS(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	result := Get(testServer.URL).
+		CertNotExpiringWithin(time.Minute).
+		Run()
+
+	if result.Type != Error {
+		t.Errorf("received unexpected result type, expected '%d', got '%d'", Error, result.Type)
+	}
+}
+
+func TestFinalURLEqualsAssertion(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/a", http.RedirectHandler("/b", http.StatusFound))
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	testServer := httptest.NewServer(mux)
+
+	for id, tc := range []struct {
+		Path            string
+		ExpectedFailure bool
+	}{
+		{
+			Path:            "/b",
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "/a",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL + "/a").
+			FinalURLEquals(testServer.URL + tc.Path).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestAssertionLabel(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+
+	result := Get(testServer.URL).
+		StatusCode(http.StatusBadRequest).Label("first").
+		StatusCode(http.StatusOK).Label("login returns OK").
+		Run()
+
+	if result.Type != Failure {
+		t.Errorf("received unexpected result type, expected '%d', got '%d'", Failure, result.Type)
+	}
+
+	if result.Description != "assertion failed: login returns OK: received unexpected status code, exepcted 200 but received 400" {
+		t.Errorf("received unexpected result description: '%s'", result.Description)
+	}
+}
+
+func TestAssertionAsWarning(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	result := Get(testServer.URL).
+		StatusCode(http.StatusOK).AsWarning().Label("prefers OK").
+		StatusCode(http.StatusAccepted).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if !slices.Equal(result.Warnings, []string{"prefers OK: received unexpected status code, exepcted 200 but received 202"}) {
+		t.Errorf("received unexpected warnings: %v", result.Warnings)
+	}
+
+	result = Get(testServer.URL).
+		StatusCode(http.StatusOK).AsWarning().
+		StatusCode(http.StatusOK).
+		Run()
+
+	if result.Type != Failure || len(result.Warnings) != 1 {
+		t.Errorf("expected failure of assertion not marked as warning, received %v", *result)
+	}
+
+	result = Get(testServer.URL).StatusCode(http.StatusAccepted).AsWarning().Run()
+	if result.Type != Success || result.Warnings != nil {
+		t.Errorf("expected no warnings, received %v", *result)
+	}
+}
+
+func TestCustomAssertion(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	}))
+
+	for id, tc := range []struct {
+		Expected        string
+		ExpectedFailure bool
+	}{
+		{
+			Expected:        "body",
+			ExpectedFailure: false,
+		},
+		{
+			Expected:        "other",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			Assert(func(response *http.Response, body []byte) *Result {
+				if string(body) != tc.Expected {
+					return &Result{
+						Type: Failure,
+					}
+				}
+				return &Result{
+					Type: Success,
+				}
+			}).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestNotModifiedAssertion(t *testing.T) {
+	etag := `"v1"`
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for id, tc := range []struct {
+		ETag            string
+		ExpectedFailure bool
+	}{
+		{
+			ETag:            etag,
+			ExpectedFailure: false,
+		},
+		{
+			ETag:            `"v0"`,
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			IfNoneMatch(tc.ETag).
+			NotModified().
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestWithHeaders(t *testing.T) {
+	host := "example.com"
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Key") != "value" || r.Header.Get("Other") != "other" {
+			t.Errorf("did not receive replaced headers in request: %v", r.Header)
+		}
+
+		if r.Header.Get("Removed") != "" {
+			t.Error("received header which should have been replaced")
+		}
+
+		if r.Host != host {
+			t.Errorf("received unexpected host, expected '%s' but received '%s'", host, r.Host)
+		}
+	}))
+
+	Get(testServer.URL).
+		Header("Removed", "value").
+		WithHeaders(http.Header{
+			"Key":   []string{"value"},
+			"Other": []string{"other"},
+			"Host":  []string{host},
+		}).
+		Run()
+}
+
+type roundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestNilResponseHandling(t *testing.T) {
+	r := Get("http://localhost").
+		StatusCode(http.StatusOK).
+		BodyIsEmpty().
+		BodyIsJson().
+		CertNotExpiringWithin(time.Minute).
+		FinalURLEquals("http://localhost")
+
+	if result := r.checkAssertions(nil); result.Type != Error {
+		t.Errorf("received unexpected result type for nil response, expected '%d', got '%d'", Error, result.Type)
+	}
+
+	if err := r.readBody(&http.Response{}); err != nil || r.responseBody == nil || len(r.responseBody) != 0 {
+		t.Errorf("expected nil body to be read as empty: %v", err)
+	}
+
+	if result := r.checkAssertions(&http.Response{StatusCode: http.StatusOK}); result.Type == Success {
+		t.Errorf("received unexpected result type for crafted response: %v", *result)
+	}
+}
+
+func TestNilBodyTransport(t *testing.T) {
+	r := Get("http://localhost").
+		StatusCode(http.StatusOK).
+		BodyIsEmpty().
+		Transport(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Request:    request,
+			}, nil
+		}))
+
+	if result := r.Run(); result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var received *http.Request
+	result := Post("http://mock.invalid/items").
+		Body([]byte(`{"name":"item"}`)).
+		Header("X-Request", "mocked").
+		Transport(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			received = request
+			return &http.Response{
+				StatusCode: http.StatusCreated,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"id":5}`)),
+				Request:    request,
+			}, nil
+		})).
+		StatusCode(http.StatusCreated).
+		JsonFieldEquals("id", 5).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if received == nil || received.Header.Get("X-Request") != "mocked" {
+		t.Errorf("expected the request to be sent using the transport: %v", received)
+	}
+
+	result = Get("http://mock.invalid").
+		Timeout(1).
+		Transport(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			<-request.Context().Done()
+			return nil, request.Context().Err()
+		})).
+		Run()
+
+	if result.Type != Timeout {
+		t.Errorf("expected the timeout to apply to the transport: %v", *result)
+	}
+}
+
+func TestVars(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := Get(testServer.URL).Iterations(2)
+	r.PreRequest(func() *Result {
+		if _, ok := r.Vars()["token"]; !ok {
+			r.Vars()["token"] = "value"
+			r.Vars()["attempts"] = 0
+		}
+		return &Result{
+			Type: Success,
+		}
+	}).
+		Test(func(response *http.Response, args ...any) Result {
+			if r.Vars()["token"] != "value" {
+				t.Errorf("did not receive variable from pre request func: %v", r.Vars())
+			}
+
+			r.Vars()["attempts"] = r.Vars()["attempts"].(int) + 1
+			if r.Vars()["attempts"].(int) < 2 {
+				return Result{
+					Type: Repeat,
+				}
+			}
+
+			return Result{
+				Type: Success,
+			}
+		})
+
+	if result := r.Run(); result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if r.Vars()["attempts"] != 2 {
+		t.Errorf("expected variables to be preserved across repeats: %v", r.Vars())
+	}
+
+	r.Iterations(2).Run()
+	if r.Vars()["attempts"] != 2 {
+		t.Errorf("expected variables to be reset between runs: %v", r.Vars())
+	}
+}
+
+func TestRequestGroupSample(t *testing.T) {
+	var hits int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+
+	group := &RequestGroup{}
+	for range 100 {
+		group.AddRequest(Get(testServer.URL))
+	}
+
+	if result := group.Sample(0.25, 1).Run(); result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if hits != 25 {
+		t.Errorf("expected 25 requests to run, %d ran", hits)
+	}
+
+	first := group.selected()
+	second := group.selected()
+	if !slices.Equal(first, second) {
+		t.Error("expected the same requests to be selected given the same seed")
+	}
+}
+
+func TestServedFromCacheAssertion(t *testing.T) {
+	for id, tc := range []struct {
+		Headers      map[string]string
+		CacheHeaders map[string]string
+		Cached       bool
+	}{
+		{
+			Headers: map[string]string{"Age": "12"},
+			Cached:  true,
+		},
+		{
+			Headers: map[string]string{"X-Cache": "Hit from cloudfront"},
+			Cached:  true,
+		},
+		{
+			Headers:      map[string]string{"Cf-Cache-Status": "HIT"},
+			CacheHeaders: map[string]string{"CF-Cache-Status": "hit"},
+			Cached:       true,
+		},
+		{
+			Headers: map[string]string{"Age": "0", "X-Cache": "MISS"},
+			Cached:  false,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for k, v := range tc.Headers {
+				w.Header().Set(k, v)
+			}
+		}))
+
+		cacheResult := Get(testServer.URL).
+			CacheHeaders(tc.CacheHeaders).
+			ServedFromCache().
+			Run()
+
+		if isFailure(cacheResult, !tc.Cached) {
+			t.Errorf("(%d) %v", id, *cacheResult)
+		}
+
+		originResult := Get(testServer.URL).
+			CacheHeaders(tc.CacheHeaders).
+			ServedFromOrigin().
+			Run()
+
+		if isFailure(originResult, tc.Cached) {
+			t.Errorf("(%d) %v", id, *originResult)
+		}
+	}
+}
+
+func TestBodyFunc(t *testing.T) {
+	var bodies []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, string(b))
+	}))
+
+	result := Post(testServer.URL).
+		Iterations(2).
+		BodyFunc(func(iteration int) []byte {
+			return []byte(fmt.Sprintf("body %d", iteration))
+		}).
+		Test(func(response *http.Response, args ...any) Result {
+			if len(bodies) < 2 {
+				return Result{
+					Type: Repeat,
+				}
+			}
+			return Result{
+				Type: Success,
+			}
+		}).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if !slices.Equal(bodies, []string{"body 1", "body 2"}) {
+		t.Errorf("received unexpected bodies: %v", bodies)
+	}
+}
+
+func TestSkipBodyRead(t *testing.T) {
+	total := 64 << 20
+	written := make(chan int, 1)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := make([]byte, 32<<10)
+		var n int
+		for n < total {
+			m, err := w.Write(chunk)
+			n += m
+			if err != nil {
+				break
+			}
+		}
+		written <- n
+	}))
+
+	result := Get(testServer.URL).
+		SkipBodyRead().
+		StatusCode(http.StatusOK).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if n := <-written; n >= total {
+		t.Errorf("expected body to not be fully read, %d of %d bytes were written", n, total)
+	}
+
+	result = Get(testServer.URL).
+		SkipBodyRead().
+		BodyIsJson().
+		Run()
+
+	if result.Type != Error {
+		t.Errorf("received unexpected result type, expected '%d', got '%d'", Error, result.Type)
+	}
+	<-written
+}
+
+func TestHeaderEqualsAssertion(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Key", "value")
+	}))
+
+	for id, tc := range []struct {
+		Value           string
+		ExpectedFailure bool
+	}{
+		{
+			Value:           "value",
+			ExpectedFailure: false,
+		},
+		{
+			Value:           "other",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			HeaderEquals("Key", tc.Value).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	allowedOrigin := "https://example.com"
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			t.Errorf("received unexpected method: %s", r.Method)
+		}
+
+		if r.Header.Get("Access-Control-Request-Method") == "" {
+			t.Error("did not receive preflight request method header")
+		}
+
+		if r.Header.Get("Origin") == allowedOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for id, tc := range []struct {
+		Origin          string
+		Method          string
+		ExpectedFailure bool
+	}{
+		{
+			Origin:          allowedOrigin,
+			Method:          http.MethodPost,
+			ExpectedFailure: false,
+		},
+		{
+			Origin:          allowedOrigin,
+			Method:          http.MethodDelete,
+			ExpectedFailure: true,
+		},
+		{
+			Origin:          "https://other.com",
+			Method:          http.MethodGet,
+			ExpectedFailure: true,
+		},
+	} {
+		result := Options(testServer.URL).
+			CORSPreflight(tc.Origin, tc.Method).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestAssertionSet(t *testing.T) {
+	jsonOK := AssertionSet().
+		StatusCode(http.StatusOK).
+		BodyIsJson()
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/invalid" {
+			w.Write([]byte("Non json response"))
+			return
+		}
+		w.Write([]byte(`{"key": "value"}`))
+	}))
+
+	for id, tc := range []struct {
+		Path            string
+		ExpectedFailure bool
+	}{
+		{
+			Path:            "/valid",
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "/invalid",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL + tc.Path).
+			Apply(jsonOK).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	codes := map[int]ResultType{}
+	for _, tc := range []struct {
+		Type     ResultType
+		Expected int
+	}{
+		{Type: Success, Expected: 0},
+		{Type: Failure, Expected: 1},
+		{Type: Error, Expected: 2},
+		{Type: Stop, Expected: 3},
+		{Type: Skip, Expected: 4},
+		{Type: Repeat, Expected: 5},
+		{Type: NoTest, Expected: 6},
+		{Type: Timeout, Expected: 7},
+	} {
+		code := ExitCode(&Result{Type: tc.Type})
+		if code != tc.Expected {
+			t.Errorf("received unexpected exit code for %s, expected %d but received %d", tc.Type, tc.Expected, code)
+		}
+
+		if other, ok := codes[code]; ok {
+			t.Errorf("exit code %d is shared by %s and %s", code, other, tc.Type)
+		}
+		codes[code] = tc.Type
+	}
+}
+
+func TestIsChunkedAssertion(t *testing.T) {
+	for id, tc := range []struct {
+		ExpectedFailure bool
+	}{
+		{
+			ExpectedFailure: false,
+		},
+		{
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tc.ExpectedFailure {
+				w.Header().Set("Content-Length", "4")
+				w.Write([]byte("body"))
+				return
+			}
+
+			w.Write([]byte("first"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("second"))
+		}))
+
+		result := Get(testServer.URL).
+			IsChunked().
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestBodyIsValidUTF8Assertion(t *testing.T) {
+	for id, tc := range []struct {
+		Body            []byte
+		ExpectedFailure bool
+	}{
+		{
+			Body:            []byte("valid åäö"),
+			ExpectedFailure: false,
+		},
+		{
+			Body:            []byte{'a', 'b', 0xff, 'c'},
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(tc.Body)
+		}))
+
+		result := Get(testServer.URL).
+			BodyIsValidUTF8().
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+
+		if tc.ExpectedFailure && result.Description != "assertion failed: received invalid utf-8 body, first invalid byte at offset 2" {
+			t.Errorf("(%d) received unexpected result description: '%s'", id, result.Description)
+		}
+	}
+}
+
+func TestMaxTimeToFirstByteAssertion(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for id, tc := range []struct {
+		Max             time.Duration
+		ExpectedFailure bool
+	}{
+		{
+			Max:             5 * time.Second,
+			ExpectedFailure: false,
+		},
+		{
+			Max:             10 * time.Millisecond,
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			MaxTimeToFirstByte(tc.Max).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestMaxTimeToFirstByteNotMeasured(t *testing.T) {
+	transport := roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: request}, nil
+	})
+
+	result := Get("http://placeholder").
+		Transport(transport).
+		MaxTimeToFirstByte(5 * time.Second).
+		Run()
+
+	if result.Type != Error || !errors.Is(result.Err, ErrValueMissing) {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	result = Get("http://placeholder").
+		Transport(transport).
+		MaxTimeToFirstByte(5 * time.Second).SkipIfMissing().
+		StatusCode(http.StatusOK).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestRestrictRedirects(t *testing.T) {
+	target := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	httpServer := httptest.NewServer(target)
+	httpsServer := httptest.NewTLSServer(target)
+	upgradeServer := httptest.NewServer(http.RedirectHandler(httpsServer.URL, http.StatusFound))
+	downgradeServer := httptest.NewTLSServer(http.RedirectHandler(httpServer.URL, http.StatusFound))
+
+	for id, tc := range []struct {
+		URL             string
+		SameHost        bool
+		AllowDowngrade  bool
+		ExpectedBlocked bool
+	}{
+		{
+			URL:             downgradeServer.URL,
+			SameHost:        false,
+			AllowDowngrade:  false,
+			ExpectedBlocked: true,
+		},
+		{
+			URL:             downgradeServer.URL,
+			SameHost:        false,
+			AllowDowngrade:  true,
+			ExpectedBlocked: false,
+		},
+		{
+			URL:             upgradeServer.URL,
+			SameHost:        true,
+			AllowDowngrade:  true,
+			ExpectedBlocked: true,
+		},
+		{
+			URL:             upgradeServer.URL,
+			SameHost:        false,
+			AllowDowngrade:  false,
+			ExpectedBlocked: false,
+		},
+	} {
+		result := Get(tc.URL).
+			TLSConfig(&tls.Config{InsecureSkipVerify: true}).
+			RestrictRedirects(tc.SameHost, tc.AllowDowngrade).
+			StatusCode(http.StatusOK).
+			Run()
+
+		if tc.ExpectedBlocked {
+			if result.Type != Error || !errors.Is(result.Err, ErrRedirectNotAllowed) {
+				t.Errorf("(%d) expected redirect to be blocked: %v", id, *result)
+			}
+		} else if result.Type != Success {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	var attempts int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("body"))
+	}))
+
+	result := Get(testServer.URL).
+		Iterations(3).
+		Test(func(response *http.Response, args ...any) Result {
+			if response.StatusCode != http.StatusOK {
+				return Result{
+					Type: Repeat,
+				}
+			}
+			return Result{
+				Type: Success,
+			}
+		}).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if result.Metrics == nil {
+		t.Fatal("expected metrics in result")
+	}
+
+	if result.Metrics.Attempts != 3 || result.Metrics.BytesReceived != 4 || result.Metrics.StatusCode != http.StatusOK || result.Metrics.NetworkCalls != 3 || result.Metrics.Duration <= 0 {
+		t.Errorf("received unexpected metrics: %+v", *result.Metrics)
+	}
+}
+
+func TestAssertNetworkCalls(t *testing.T) {
+	for id, tc := range []struct {
+		Calls           int
+		ExpectedFailure bool
+	}{
+		{
+			Calls:           3,
+			ExpectedFailure: false,
+		},
+		{
+			Calls:           1,
+			ExpectedFailure: true,
+		},
+	} {
+		var attempts int
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+		}))
+
+		result := Get(testServer.URL).
+			Iterations(3).
+			AssertAfterTest(true).
+			AssertNetworkCalls(tc.Calls).
+			Test(func(response *http.Response, args ...any) Result {
+				if attempts < 3 {
+					return Result{
+						Type: Repeat,
+					}
+				}
+				return Result{
+					Type: Success,
+				}
+			}).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestAssertCacheable(t *testing.T) {
+	lastModified := time.Now().UTC().Truncate(time.Second)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/etag":
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/modified":
+			http.ServeContent(w, r, "resource", lastModified, strings.NewReader("body"))
+			return
+		case "/ignored":
+			w.Header().Set("ETag", `"v1"`)
+		}
+		w.Write([]byte("body"))
+	}))
+
+	for id, tc := range []struct {
+		Path            string
+		ExpectedFailure bool
+	}{
+		{
+			Path:            "/etag",
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "/modified",
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "/ignored",
+			ExpectedFailure: true,
+		},
+		{
+			Path:            "/uncacheable",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL + tc.Path).
+			StatusCode(http.StatusOK).
+			AssertCacheable().
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestAssertCacheableConditionalRequest(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Header.Get("X-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+
+	r := Get(testServer.URL).
+		Header("X-Token", "token").
+		AssertCacheable().
+		MaxDuration(100 * time.Millisecond).
+		AssertNetworkCalls(2)
+	if result := r.Run(); result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if r.headers.Get("If-None-Match") != "" {
+		t.Errorf("expected the headers of the request to be unchanged, received %v", r.headers)
+	}
+
+	result := Get("http://placeholder").
+		Transport(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			if request.Header.Get("If-None-Match") != "" {
+				return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Request: request}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Etag": {`"v1"`}}, Request: request}, nil
+		})).
+		AssertCacheable().
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestAssertCacheableReusesConnection(t *testing.T) {
+	var conns int
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("body"))
+	}))
+	testServer.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns++
+		}
+	}
+	testServer.Start()
+	defer testServer.Close()
+
+	var body []byte
+	result := Get(testServer.URL).
+		AssertCacheable().
+		Test(func(response *http.Response, args ...any) Result {
+			body, _ = io.ReadAll(response.Body)
+			return Result{
+				Type: Success,
+			}
+		}).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if conns != 1 {
+		t.Errorf("expected the conditional request to reuse the connection, received %d connections", conns)
+	}
+
+	if string(body) != "body" {
+		t.Errorf("expected the body to be kept for the test, received '%s'", body)
+	}
+}
+
+func TestConnectionReused(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	}))
+
+	results := map[string]*Result{}
+	capture := func(id string) func(*Result) *Result {
+		return func(r *Result) *Result {
+			results[id] = r
+			return r
+		}
+	}
+
+	group := (&RequestGroup{}).ShareConnections()
+	group.AddRequest(Get(testServer.URL).Id("first").StatusCode(http.StatusOK).PostRequest(capture("first")))
+	group.AddRequest(Get(testServer.URL).Id("second").ConnectionReused().PostRequest(capture("second")))
+	group.Run()
+
+	if results["second"] == nil || results["second"].Type != Success {
+		t.Errorf("expected second request to reuse the connection: %v", results["second"])
+	}
+
+	result := Get(testServer.URL).
+		TLSConfig(&tls.Config{}).
+		ConnectionReused().
+		Run()
+
+	if result.Type != Failure {
+		t.Errorf("expected request with its own transport to use a new connection: %v", *result)
+	}
+}
+
+func TestShareConnectionsTransportOptions(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/legacy" && r.Proto != "HTTP/1.0" {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+		}
+	})
+	testServer := httptest.NewServer(handler)
+	tlsServer := httptest.NewTLSServer(handler)
+
+	shared := Get(testServer.URL).Id("shared").StatusCode(http.StatusOK)
+	group := (&RequestGroup{}).ShareConnections()
+	group.AddRequest(shared)
+	group.AddRequest(Get(tlsServer.URL).Id("tls").TLSConfig(tlsServer.Client().Transport.(*http.Transport).TLSClientConfig).StatusCode(http.StatusOK))
+	group.AddRequest(Get(testServer.URL + "/legacy").Id("legacy").HTTP10().StatusCode(http.StatusOK))
+
+	if result := group.Run(); result.Type != Success {
+		t.Errorf("expected the transport level options of the requests to take effect: %v", group.Report())
+	}
+
+	if shared.transport != nil {
+		t.Error("expected the shared transport to be cleared after the group run")
+	}
+}
+
+func TestRawStatusLineAssertion(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	for id, tc := range []struct {
+		Expected        string
+		ExpectedFailure bool
+	}{
+		{
+			Expected:        "HTTP/1.1 404 Not Found",
+			ExpectedFailure: false,
+		},
+		{
+			Expected:        "HTTP/1.1 404 not found",
+			ExpectedFailure: true,
+		},
+		{
+			Expected:        "HTTP/1.0 404 Not Found",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			RawStatusLine(tc.Expected).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestBodyEqualsAssertion(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	}))
+
+	for id, tc := range []struct {
+		Expected        string
+		ExpectedFailure bool
+	}{
+		{
+			Expected:        "body",
+			ExpectedFailure: false,
+		},
+		{
+			Expected:        "other",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			BodyEquals([]byte(tc.Expected)).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestBodyTextEqualsAssertion(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	}))
+
+	for id, tc := range []struct {
+		Expected        string
+		ExpectedFailure bool
+	}{
+		{
+			Expected:        "ok",
+			ExpectedFailure: false,
+		},
+		{
+			Expected:        "  ok\r\n",
+			ExpectedFailure: false,
+		},
+		{
+			Expected:        "o k",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			BodyTextEquals(tc.Expected).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestBodySHA256Assertion(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	}))
+
+	for id, tc := range []struct {
+		Digest          string
+		ExpectedFailure bool
+	}{
+		{
+			Digest:          "230d8358dc8e8890b4c58deeb62912ee2f20357ae92a5cc861b98e68fe31acb5",
+			ExpectedFailure: false,
+		},
+		{
+			Digest:          "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			BodySHA256(tc.Digest).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestModifyRequest(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Modified") != "true" {
+			t.Error("did not receive header added by modify request func")
+		}
+
+		if !r.Close {
+			t.Error("expected connection to be closed by modify request func")
+		}
+	}))
+
+	Get(testServer.URL).
+		ModifyRequest(func(request *http.Request) {
+			request.Header.Set("Modified", "true")
+			request.Close = true
+		}).
+		Run()
+}
+
+func TestRequestGroupRepeat(t *testing.T) {
+	for id, tc := range []struct {
+		FailOnHit         int
+		ContinueOnFailure bool
+		ExpectedHits      int
+		ExpectedFailure   bool
+	}{
+		{
+			FailOnHit:       0,
+			ExpectedHits:    6,
+			ExpectedFailure: false,
+		},
+		{
+			FailOnHit:       3,
+			ExpectedHits:    4,
+			ExpectedFailure: true,
+		},
+		{
+			FailOnHit:         3,
+			ContinueOnFailure: true,
+			ExpectedHits:      6,
+			ExpectedFailure:   true,
+		},
+	} {
+		var hits int
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			if hits == tc.FailOnHit {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+
+		group := (&RequestGroup{}).
+			Repeat(3).
+			ContinueOnFailure(tc.ContinueOnFailure)
+		group.AddRequest(Get(testServer.URL).Id("first").StatusCode(http.StatusOK))
+		group.AddRequest(Get(testServer.URL).Id("second").StatusCode(http.StatusOK))
+
+		result := group.Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+
+		if hits != tc.ExpectedHits {
+			t.Errorf("(%d) expected %d hits, received %d", id, tc.ExpectedHits, hits)
+		}
+
+		if len(group.Report().Results) != tc.ExpectedHits {
+			t.Errorf("(%d) expected %d results in report, received %d", id, tc.ExpectedHits, len(group.Report().Results))
+		}
+	}
+}
+
+func TestRequestGroupSingleRun(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	group := &RequestGroup{}
+	group.AddRequest(Get(testServer.URL).Id("failing").StatusCode(http.StatusOK))
+
+	if result := group.Run(); result.Type != Success {
+		t.Errorf("expected a single run to succeed regardless of request results: %v", *result)
+	}
+
+	if ids := group.Report().Ids(Failure); !slices.Equal(ids, []string{"failing"}) {
+		t.Errorf("received unexpected failed requests: %v", ids)
+	}
+}
+
+func TestLatencyStableAssertion(t *testing.T) {
+	for id, tc := range []struct {
+		Delays          []time.Duration
+		ExpectedFailure bool
+	}{
+		{
+			Delays:          []time.Duration{20 * time.Millisecond},
+			ExpectedFailure: false,
+		},
+		{
+			Delays:          []time.Duration{time.Millisecond, 100 * time.Millisecond},
+			ExpectedFailure: true,
+		},
+	} {
+		var hits int
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(tc.Delays[hits%len(tc.Delays)])
+			hits++
+		}))
+
+		result := Get(testServer.URL).
+			LatencyStable(6, 0.5).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+
+		if hits != 6 {
+			t.Errorf("(%d) expected 6 samples, received %d", id, hits)
+		}
+	}
+}
+
+func TestLatencyStableSamples(t *testing.T) {
+	var hits int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+
+	result := Get(testServer.URL).
+		RetryOn(http.StatusServiceUnavailable).
+		Iterations(2).
+		LatencyStable(3, 0.5).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("expected the retried attempt not to be sampled: %v", *result)
+	}
+
+	if hits != 4 {
+		t.Errorf("expected 4 requests, received %d", hits)
+	}
+
+	for _, samples := range []int{0, -1} {
+		result := Get(testServer.URL).
+			LatencyStable(samples, 0.5).
+			Run()
+
+		if result.Type != Error {
+			t.Errorf("(%d) received unexpected result: %v", samples, *result)
+		}
+	}
+}
+
+func TestCoefficientOfVariation(t *testing.T) {
+	if cv := coefficientOfVariation([]time.Duration{2, 4, 4, 4, 5, 5, 7, 9}); cv != 0.4 {
+		t.Errorf("received unexpected coefficient of variation: %f", cv)
+	}
+}
+
+func TestHTTP10(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Proto != "HTTP/1.0" {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		w.Write(b)
+	})
+	testServer := httptest.NewServer(handler)
+	tlsServer := httptest.NewTLSServer(handler)
+
+	for id, tc := range []struct {
+		Request         *Request
+		ExpectedFailure bool
+	}{
+		{
+			Request:         Get(testServer.URL).HTTP10().StatusCode(http.StatusOK),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Post(testServer.URL).HTTP10().Body([]byte(`{"id":1}`)).StatusCode(http.StatusOK).JsonFieldEquals("id", 1.0),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(tlsServer.URL).HTTP10().TLSConfig(tlsServer.Client().Transport.(*http.Transport).TLSClientConfig).StatusCode(http.StatusOK),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).StatusCode(http.StatusOK),
+			ExpectedFailure: true,
+		},
+	} {
+		result := tc.Request.Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "jobbigt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "test.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/containers/json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	testServer.Listener.Close()
+	testServer.Listener = listener
+	testServer.Start()
+	defer testServer.Close()
+
+	result := Get("http://placeholder/containers/json").
+		UnixSocket(listener.Addr().String()).
+		StatusCode(http.StatusOK).
+		BodyIsJson().
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestTimeoutResultType(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+	}))
+	defer testServer.Close()
+
+	result := Get(testServer.URL).
+		Timeout(1).
+		Run()
+
+	if result.Type != Timeout {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	group := &RequestGroup{}
+	group.AddRequest(Get(testServer.URL).Id("slow").Timeout(1))
+	group.Run()
+	if ids := group.Report().Ids(Timeout); !slices.Equal(ids, []string{"slow"}) {
+		t.Errorf("received unexpected timed out requests: %v", ids)
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer testServer.Close()
+
+	result := Get(testServer.URL).
+		ResponseHeaderTimeout(50 * time.Millisecond).
+		Run()
+
+	if result.Type != Timeout {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestDumpOnFailure(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Server", "test")
+		w.Write([]byte("response body"))
+	}))
+	defer testServer.Close()
+
+	var dump bytes.Buffer
+	result := Post(testServer.URL).
+		Body([]byte("request body")).
+		StatusCode(http.StatusOK).
+		DumpOnFailure(&dump).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+	if dump.Len() != 0 {
+		t.Errorf("expected nothing to be dumped on success, received: %s", dump.String())
+	}
+
+	result = Post(testServer.URL).
+		Body([]byte("request body")).
+		StatusCode(http.StatusCreated).
+		DumpOnFailure(&dump).
+		Run()
+
+	if result.Type != Failure {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+	for _, expected := range []string{"POST / HTTP/1.1", "request body", "HTTP/1.1 200 OK", "X-Server: test", "response body"} {
+		if !strings.Contains(dump.String(), expected) {
+			t.Errorf("expected dump to contain '%s', received: %s", expected, dump.String())
+		}
+	}
+}
+
+func TestConnectionDeadline(t *testing.T) {
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a"))
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/stall" {
+			<-release
+		}
+	}))
+	defer testServer.Close()
+	defer close(release)
+
+	result := Get(testServer.URL).
+		ConnectionDeadline(100 * time.Millisecond).
+		BodyEquals([]byte("a")).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	start := time.Now()
+	result = Get(testServer.URL + "/stall").
+		ConnectionDeadline(100 * time.Millisecond).
+		Run()
+
+	if result.Type != Timeout || !errors.Is(result.Err, os.ErrDeadlineExceeded) {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the stalled connection to fail fast, took %v", elapsed)
+	}
+}
+
+func TestBodyContainsAssertion(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("an error occurred\npanic: runtime error at main.go:12"))
+	}))
+
+	for id, tc := range []struct {
+		Substr      string
+		Contained   bool
+		Description string
+	}{
+		{
+			Substr:      "panic:",
+			Contained:   true,
+			Description: "assertion failed: response body contains 'panic:' at offset 18: 'an error occurred\npanic: runtime error at ma'",
+		},
+		{
+			Substr:    "secret",
+			Contained: false,
+		},
+	} {
+		containsResult := Get(testServer.URL).
+			BodyContains(tc.Substr).
+			Run()
+
+		if isFailure(containsResult, !tc.Contained) {
+			t.Errorf("(%d) %v", id, *containsResult)
+		}
+
+		notContainsResult := Get(testServer.URL).
+			BodyNotContains(tc.Substr).
+			Run()
+
+		if isFailure(notContainsResult, tc.Contained) {
+			t.Errorf("(%d) %v", id, *notContainsResult)
+		}
+
+		if tc.Contained && notContainsResult.Description != tc.Description {
+			t.Errorf("(%d) received unexpected result description: '%s'", id, notContainsResult.Description)
+		}
+	}
+}
+
+func TestRawPath(t *testing.T) {
+	var received string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.URL.Path + "?" + r.URL.RawQuery
+	}))
+
+	for id, path := range []string{"/foo/../bar", "//foo/../bar", "/a/./b/%2e%2e/c"} {
+		Get(testServer.URL + "/ignored?x=1").
+			RawPath(path).
+			Run()
+
+		expected := path + "?x=1"
+		if path == "/a/./b/%2e%2e/c" {
+			expected = "/a/./b/../c?x=1"
+		}
+
+		if received != expected {
+			t.Errorf("(%d) received unexpected path, expected '%s' but received '%s'", id, expected, received)
+		}
+	}
+}
+
+func TestModifyResponse(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server-Version", "v1.2.3-build45")
+		w.Write([]byte("body"))
+	}))
+
+	result := Get(testServer.URL).
+		ModifyResponse(func(response *http.Response) {
+			version, _, _ := strings.Cut(response.Header.Get("Server-Version"), "-")
+			response.Header.Set("Server-Version", version)
+			response.Header.Set("Modified", "true")
+		}).
+		HeaderEquals("Server-Version", "v1.2.3").
+		HeaderEquals("Modified", "true").
+		BodyEquals([]byte("body")).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+type fakeTB struct {
+	testing.TB
+	calls []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.calls = append(f.calls, "Errorf: "+fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Fatalf(format string, args ...any) {
+	f.calls = append(f.calls, "Fatalf: "+fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Skipf(format string, args ...any) {
+	f.calls = append(f.calls, "Skipf: "+fmt.Sprintf(format, args...))
+}
+
+func TestRunT(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for id, tc := range []struct {
+		Request  *Request
+		Expected []string
+	}{
+		{
+			Request:  Get(testServer.URL).Id("ok").StatusCode(http.StatusOK),
+			Expected: nil,
+		},
+		{
+			Request:  Get(testServer.URL).Id("failure").StatusCode(http.StatusCreated),
+			Expected: []string{"Errorf: request failure failed: assertion failed: received unexpected status code, exepcted 201 but received 200"},
+		},
+		{
+			Request:  (&Request{id: "error", method: http.MethodGet}),
+			Expected: []string{"Fatalf: request error received an error: url is required"},
+		},
+	} {
+		tb := &fakeTB{}
+		tc.Request.RunT(tb)
+
+		if !slices.Equal(tb.calls, tc.Expected) {
+			t.Errorf("(%d) received unexpected calls: %q", id, tb.calls)
+		}
+	}
+
+	Get(testServer.URL).StatusCode(http.StatusOK).RunT(t)
+}
+
+func TestExtractHeader(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/items/42")
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/items/42", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	testServer := httptest.NewServer(mux)
+
+	group := &RequestGroup{}
+	group.AddRequest(Post(testServer.URL+"/items").Id("create").ExtractHeader("location", "Location"))
+	group.AddRequest(Get(testServer.URL + "{{req.create.location}}").Id("fetch").StatusCode(http.StatusOK))
+
+	if result := group.Run(); result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	result := Get(testServer.URL+"/items/42").
+		ExtractHeader("location", "Location").
+		Run()
+
+	if result.Type != Failure {
+		t.Errorf("received unexpected result type, expected '%d', got '%d'", Failure, result.Type)
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	var keys []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+	}))
+
+	for _, body := range []string{`{"amount":5}`, `{"amount":5}`, `{"amount":6}`} {
+		Post(testServer.URL).
+			Body([]byte(body)).
+			IdempotencyKey(true, "").
+			Run()
+	}
+
+	if keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("expected the same key for the same body, received: %v", keys)
+	}
+	if keys[0] == keys[2] {
+		t.Errorf("expected different keys for different bodies, received: %v", keys)
+	}
+
+	keys = nil
+	r := Post(testServer.URL).
+		Body([]byte(`{"amount":5}`)).
+		Iterations(2).
+		IdempotencyKey(false, "Idempotency-Key").
+		Test(func(response *http.Response, args ...any) Result {
+			return Result{
+				Type: Repeat,
+			}
+		})
+	r.Run()
+	r.Run()
+
+	if len(keys) != 4 || keys[0] == "" || keys[0] != keys[1] || keys[2] != keys[3] {
+		t.Errorf("expected the same key for all attempts of a run, received: %v", keys)
+	}
+	if keys[0] == keys[2] {
+		t.Errorf("expected different keys for different runs, received: %v", keys)
+	}
+}
+
+func TestCompressionAssertions(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Write([]byte("body"))
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte("body"))
+		gz.Close()
+	}))
+
+	for id, tc := range []struct {
+		Request         *Request
+		ExpectedFailure bool
+	}{
+		{
+			Request:         Get(testServer.URL).Header("Accept-Encoding", "gzip").IsCompressed().HeaderEquals("Content-Encoding", "gzip"),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).IsCompressed(),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).Header("Accept-Encoding", "gzip").IsNotCompressed(),
+			ExpectedFailure: true,
+		},
+		{
+			Request:         Get(testServer.URL+"/plain").Header("Accept-Encoding", "gzip").IsNotCompressed(),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL + "/plain").IsCompressed(),
+			ExpectedFailure: true,
+		},
+	} {
+		result := tc.Request.
+			BodyEquals([]byte("body")).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	var bodies []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+	}))
+
+	run := func(seed int64) {
+		r := Post(testServer.URL).Seed(seed).Iterations(2)
+		r.BodyFunc(func(iteration int) []byte {
+			return []byte(fmt.Sprintf(`{"amount":%d}`, r.Rand().Intn(1000000)))
+		}).Test(func(response *http.Response, args ...any) Result {
+			return Result{
+				Type: Repeat,
+			}
+		})
+		r.Run()
+		r.Run()
+	}
+
+	run(42)
+	if len(bodies) != 4 || bodies[0] != bodies[2] || bodies[1] != bodies[3] {
+		t.Errorf("expected runs with the same seed to produce identical bodies, received %v", bodies)
+	}
+	if bodies[0] == bodies[1] {
+		t.Errorf("expected iterations to produce different bodies, received %v", bodies)
+	}
+
+	first := bodies[0]
+	bodies = nil
+	run(43)
+	if bodies[0] == first {
+		t.Errorf("expected a different seed to produce a different body, received %v", bodies)
+	}
+}
+
+func TestGzipBody(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "gzip" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		io.Copy(w, gz)
+	}))
+
+	result := Post(testServer.URL).
+		GzipBody().
+		JsonBody(map[string]any{"amount": 5}).
+		StatusCode(http.StatusOK).
+		BodyEquals([]byte(`{"amount":5}`)).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	result = Post(testServer.URL).
+		JsonBody(func() {}).
+		Run()
+
+	if result.Type != Error {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestBodyIsValidGzipAssertion(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("archive contents"))
+	gz.Close()
+	valid := buf.Bytes()
+
+	for id, tc := range []struct {
+		Body            []byte
+		ExpectedFailure bool
+	}{
+		{
+			Body:            valid,
+			ExpectedFailure: false,
+		},
+		{
+			Body:            valid[:len(valid)-10],
+			ExpectedFailure: true,
+		},
+		{
+			Body:            []byte("archive contents"),
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Write(tc.Body)
+		}))
+
+		result := Get(testServer.URL).
+			BodyIsValidGzip().
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestBrotliDecompression(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		br := brotli.NewWriter(w)
+		br.Write([]byte(`{"key": "value"}`))
+		br.Close()
+	}))
+
+	result := Get(testServer.URL).
+		Header("Accept-Encoding", "br").
+		IsCompressed().
+		BodyIsJson().
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestAcceptContentNegotiation(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/xml, application/json;q=0.9" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	}))
+
+	for id, tc := range []struct {
+		ContentType     string
+		ExpectedFailure bool
+	}{
+		{
+			ContentType:     "application/xml",
+			ExpectedFailure: false,
+		},
+		{
+			ContentType:     "application/json",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			Header("Accept", "text/html").
+			Accept("application/xml", "application/json;q=0.9").
+			StatusCode(http.StatusOK).
+			ContentType(tc.ContentType).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestDateWithinAssertion(t *testing.T) {
+	for id, tc := range []struct {
+		Date            string
+		SkipIfMissing   bool
+		ExpectedFailure bool
+	}{
+		{
+			Date:            time.Now().UTC().Format(http.TimeFormat),
+			ExpectedFailure: false,
+		},
+		{
+			Date:            time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat),
+			ExpectedFailure: true,
+		},
+		{
+			Date:            "yesterday",
+			ExpectedFailure: true,
+		},
+		{
+			Date:            "",
+			ExpectedFailure: true,
+		},
+		{
+			Date:            "",
+			SkipIfMissing:   true,
+			ExpectedFailure: false,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header()["Date"] = []string{tc.Date}
+		}))
+
+		r := Get(testServer.URL).DateWithin(time.Minute)
+		if tc.SkipIfMissing {
+			r.SkipIfMissing()
+		}
+		result := r.Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestContentSniffEqualsAssertion(t *testing.T) {
+	png := []byte("\x89PNG\x0D\x0A\x1A\x0Aimage data")
+
+	for id, tc := range []struct {
+		Body            []byte
+		Expected        string
+		ExpectedFailure bool
+	}{
+		{
+			Body:            png,
+			Expected:        "image/png",
+			ExpectedFailure: false,
+		},
+		{
+			Body:            []byte("<html><body></body></html>"),
+			Expected:        "text/html",
+			ExpectedFailure: false,
+		},
+		{
+			Body:            []byte("<html><body></body></html>"),
+			Expected:        "image/png",
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(tc.Body)
+		}))
+
+		result := Get(testServer.URL).
+			ContentSniffEquals(tc.Expected).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestCharsetAssertion(t *testing.T) {
+	for id, tc := range []struct {
+		ContentType     string
+		SkipIfMissing   bool
+		ExpectedFailure bool
+	}{
+		{
+			ContentType:     "text/html; charset=ISO-8859-1",
+			ExpectedFailure: false,
+		},
+		{
+			ContentType:     "text/html; charset=iso-8859-1",
+			ExpectedFailure: false,
+		},
+		{
+			ContentType:     "text/html; charset=utf-8",
+			SkipIfMissing:   true,
+			ExpectedFailure: true,
+		},
+		{
+			ContentType:     "text/html",
+			ExpectedFailure: true,
+		},
+		{
+			ContentType:     "text/html",
+			SkipIfMissing:   true,
+			ExpectedFailure: false,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tc.ContentType)
+		}))
+
+		r := Get(testServer.URL).Charset("ISO-8859-1")
+		if tc.SkipIfMissing {
+			r.SkipIfMissing()
+		}
+		result := r.Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Token", "secret")
+	}))
+
+	rr := Get(testServer.URL).
+		StatusCode(http.StatusOK).
+		ExtractHeader("token", "Token").
+		RunResult().
+		MustSucceed(t)
+
+	if rr.Type() != Success || !rr.ArgsContain("token") || rr.ArgsContain("other") || rr.Result().DownStreamArgs["token"] != "secret" {
+		t.Errorf("received unexpected result: %v", *rr.Result())
+	}
+
+	tb := &fakeTB{}
+	(&Request{method: http.MethodGet}).RunResult().MustSucceed(tb)
+
+	if !slices.Equal(tb.calls, []string{"Fatalf: expected success but received Error: url is required"}) {
+		t.Errorf("received unexpected calls: %q", tb.calls)
+	}
+}
+
+func TestAssertAfterTest(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for id, tc := range []struct {
+		AssertAfterTest bool
+		Expected        []string
+	}{
+		{
+			AssertAfterTest: false,
+			Expected:        []string{"assertion", "test"},
+		},
+		{
+			AssertAfterTest: true,
+			Expected:        []string{"test", "assertion"},
+		},
+	} {
+		var sequence []string
+		result := Get(testServer.URL).
+			AssertAfterTest(tc.AssertAfterTest).
+			Assert(func(response *http.Response, body []byte) *Result {
+				sequence = append(sequence, "assertion")
+				return &Result{
+					Type: Success,
+				}
+			}).
+			Test(func(response *http.Response, args ...any) Result {
+				sequence = append(sequence, "test")
+				return Result{
+					Type: Success,
+				}
+			}).
+			Run()
+
+		if result.Type != Success {
+			t.Errorf("(%d) %v", id, *result)
+		}
+
+		if !slices.Equal(sequence, tc.Expected) {
+			t.Errorf("(%d) received unexpected sequence: %v", id, sequence)
+		}
+	}
+}
+
+func TestRequestGroupRateLimit(t *testing.T) {
+	var hits int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+
+	group := (&RequestGroup{}).RateLimit(10)
+	for range 4 {
+		group.AddRequest(Get(testServer.URL))
+	}
+
+	start := time.Now()
+	group.Run()
+	elapsed := time.Since(start)
+
+	if hits != 4 {
+		t.Errorf("expected 4 requests, received %d", hits)
+	}
+
+	if elapsed < 300*time.Millisecond {
+		t.Errorf("expected 4 requests at 10 per second to take at least 300ms, took %s", elapsed)
+	}
+}
+
+func TestAssertIdempotent(t *testing.T) {
+	for id, tc := range []struct {
+		Counter         bool
+		ExpectedFailure bool
+	}{
+		{
+			Counter:         false,
+			ExpectedFailure: false,
+		},
+		{
+			Counter:         true,
+			ExpectedFailure: true,
+		},
+	} {
+		var hits int
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			if tc.Counter {
+				fmt.Fprintf(w, "%d", hits)
+				return
+			}
+			w.Write([]byte("stable"))
+		}))
+
+		result := Get(testServer.URL).
+			AssertIdempotent().
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+
+		if hits != 2 {
+			t.Errorf("(%d) expected 2 requests, received %d", id, hits)
+		}
+	}
+}
+
+func TestAssertIdempotentDecodedBody(t *testing.T) {
+	var hits int
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body := `{"b":1, "a":2}`
+		if hits%2 == 0 {
+			body = `{"a":2,"b":1}`
+		}
+		if r.URL.Path == "/gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(body))
+			gz.Close()
+			return
+		}
+		w.Write([]byte(body))
+	}))
+
+	for id, tc := range []struct {
+		Request         *Request
+		ExpectedFailure bool
+	}{
+		{
+			Request:         Get(testServer.URL+"/gzip").Header("Accept-Encoding", "gzip").NormalizeJson().AssertIdempotent(),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).NormalizeJson().AssertIdempotent(),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).AssertIdempotent(),
+			ExpectedFailure: true,
+		},
+	} {
+		result := tc.Request.Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+
+		if result.Metrics.NetworkCalls != 2 {
+			t.Errorf("(%d) expected 2 network calls, received %d", id, result.Metrics.NetworkCalls)
+		}
+	}
+}
+
+func TestAssertIdempotentNilBody(t *testing.T) {
+	result := Get("http://placeholder").
+		Transport(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Request: request}, nil
+		})).
+		AssertIdempotent().
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestAssertIdempotentPreservesDump(t *testing.T) {
+	var buf bytes.Buffer
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("stable"))
+	}))
+
+	var sent int
+	result := Get(testServer.URL).
+		ModifyRequest(func(request *http.Request) {
+			sent++
+			request.Header.Set("X-Sent", strconv.Itoa(sent))
+		}).
+		DumpOnFailure(&buf).
+		AssertIdempotent().
+		StatusCode(http.StatusCreated).
+		Run()
+
+	if result.Type != Failure {
+		t.Fatalf("received unexpected result: %v", *result)
+	}
+
+	if !strings.Contains(buf.String(), "X-Sent: 1") {
+		t.Errorf("expected the dump to contain the first request, received:\n%s", buf.String())
+	}
+}
+
+func TestRequestGroupRunTagged(t *testing.T) {
+	var paths []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+
+	group := &RequestGroup{}
+	group.AddRequest(Get(testServer.URL + "/a").Tag("smoke"))
+	group.AddRequest(Get(testServer.URL + "/b").Tag("slow"))
+	group.AddRequest(Get(testServer.URL+"/c").Tag("slow", "smoke"))
+	group.AddRequest(Get(testServer.URL + "/d"))
+
+	group.RunTagged("smoke")
+	if !slices.Equal(paths, []string{"/a", "/c"}) {
+		t.Errorf("received unexpected requests: %v", paths)
+	}
+
+	paths = nil
+	group.Run()
+	if len(paths) != 4 {
+		t.Errorf("expected all requests to run after a tagged run, received: %v", paths)
+	}
+}
+
+func TestRequestGroupStop(t *testing.T) {
+	var paths []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+
+	group := (&RequestGroup{}).Repeat(2)
+	group.AddRequest(Get(testServer.URL + "/first").Id("first"))
+	group.AddRequest(Get(testServer.URL + "/second").Id("second").
+		Test(func(response *http.Response, args ...any) Result {
+			return Result{
+				Type: Stop,
+			}
+		}))
+	group.AddRequest(Get(testServer.URL + "/third").Id("third"))
+
+	result := group.Run()
+	if result.Type != Stop {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if !slices.Equal(paths, []string{"/first", "/second"}) {
+		t.Errorf("received unexpected requests: %v", paths)
+	}
+}
+
+func TestRequestGroupApplyToAll(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+
+	group := &RequestGroup{}
+	group.AddRequest(Get(testServer.URL + "/fast").Id("before"))
+	group.ApplyToAll(AssertionSet().MaxDuration(100 * time.Millisecond))
+	group.AddRequest(Get(testServer.URL + "/slow").Id("after"))
+
+	group.Run()
+
+	report := group.Report()
+	if report.Results[0].Result.Type != Success {
+		t.Errorf("received unexpected result for the fast request: %v", *report.Results[0].Result)
+	}
+	if report.Results[1].Result.Type != Failure {
+		t.Errorf("received unexpected result for the slow request: %v", *report.Results[1].Result)
+	}
+}
+
+func TestFollowAndAssert(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/middle", http.StatusFound)
+		case "/middle":
+			http.Redirect(w, r, "/final", http.StatusMovedPermanently)
+		default:
+			w.Write([]byte("final"))
+		}
+	}))
+
+	for id, tc := range []struct {
+		Path            string
+		Redirect        *Request
+		ExpectedFailure bool
+	}{
+		{
+			Path:            "/start",
+			Redirect:        AssertionSet().StatusCode(http.StatusFound).HeaderEquals("Location", "/middle"),
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "/start",
+			Redirect:        AssertionSet().StatusCode(http.StatusMovedPermanently),
+			ExpectedFailure: true,
+		},
+		{
+			Path:            "/final",
+			Redirect:        AssertionSet().StatusCode(http.StatusFound),
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL + tc.Path).
+			FollowAndAssert(tc.Redirect).
+			StatusCode(http.StatusOK).
+			BodyEquals([]byte("final")).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+
+	result := Get(testServer.URL + "/start").
+		FollowAndAssert(AssertionSet().BodyIsEmpty()).
+		Run()
+
+	if result.Type != Error {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestNegotiatedProtocol(t *testing.T) {
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	testServer.EnableHTTP2 = true
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	for id, tc := range []struct {
+		Protocol        string
+		ExpectedFailure bool
+	}{
+		{
+			Protocol:        "h2",
+			ExpectedFailure: false,
+		},
+		{
+			Protocol:        "http/1.1",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			TLSConfig(&tls.Config{InsecureSkipVerify: true}).
+			NegotiatedProtocol(tc.Protocol).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestRequestGroupRunContext(t *testing.T) {
+	var paths []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	group := &RequestGroup{}
+	group.AddRequest(Get(testServer.URL + "/first").Id("first").PostRequest(func(result *Result) *Result {
+		cancel()
+		return &Result{
+			Type: Success,
+		}
+	}))
+	group.AddRequest(Get(testServer.URL + "/second").Id("second"))
+
+	result := group.RunContext(ctx)
+	if result.Type != Error || !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if !slices.Equal(paths, []string{"/first"}) {
+		t.Errorf("received unexpected requests: %v", paths)
+	}
+}
@@ -0,0 +1,761 @@
+package jobbigt
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Finds the value at the path in the json body. Returns false if the path does not exist.
+func jsonPath(body []byte, path string) (any, bool, error) {
+	var document any
+	err := json.Unmarshal(body, &document)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, ok := jsonLookup(document, path)
+	return value, ok, nil
+}
+
+// Finds the value at the path in a decoded json document. The path is a dot separated list of object keys and array indices, e.g. "items.0.id".
+// An empty path refers to the whole document. Returns false if the path does not exist.
+func jsonLookup(document any, path string) (any, bool) {
+	if path == "" {
+		return document, true
+	}
+
+	value := document
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// Returns the json type of a decoded json value, one of string, number, bool, object, array or null.
+func jsonType(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "bool"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// Finds the value at the path in the response body, returning a non nil result if the body is not json or the path does not exist.
+func (r *Request) jsonField(path string) (any, *Result) {
+	value, ok, err := jsonPath(r.responseBody, path)
+	if err != nil {
+		return nil, &Result{
+			Type:        Failure,
+			Description: fmt.Sprintf("failed to unmarshal the response body: '%s'", r.responseBody),
+			Err:         err,
+		}
+	}
+
+	if !ok {
+		return nil, &Result{
+			Type:        Failure,
+			Description: fmt.Sprintf("path '%s' does not exist in the response body", path),
+			Err:         ErrValueMissing,
+		}
+	}
+
+	return value, nil
+}
+
+// Assert that the json value at the path is of a certain type, one of string, number, bool, object, array or null.
+// A mismatch in received and expected type, or a missing path, results in a 'Failure'.
+func (r *Request) JsonFieldIsType(path, typ string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		value, result := r.jsonField(path)
+		if result != nil {
+			return result
+		}
+
+		if received := jsonType(value); received != typ {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected type at path '%s', expected %s but received %s", path, typ, received),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Compares two decoded json values which must both be numbers or both be strings.
+func compareJson(a, b any) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		return cmp.Compare(x, y), true
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return cmp.Compare(x, y), true
+	}
+	return 0, false
+}
+
+// Assert that the json array at the path consists of objects sorted by the field, in the order "asc" or "desc".
+// An unsorted array, or elements missing the field or having non comparable values, results in a 'Failure'.
+func (r *Request) JsonArraySortedBy(path, field, order string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		if order != "asc" && order != "desc" {
+			return &Result{
+				Type:        Error,
+				Description: fmt.Sprintf("invalid order '%s', expected asc or desc", order),
+			}
+		}
+
+		value, result := r.jsonField(path)
+		if result != nil {
+			return result
+		}
+
+		array, ok := value.([]any)
+		if !ok {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("value at path '%s' is not an array but %s", path, jsonType(value)),
+			}
+		}
+
+		var previous any
+		for i, element := range array {
+			current, ok := jsonLookup(element, field)
+			if !ok {
+				return &Result{
+					Type:        Failure,
+					Description: fmt.Sprintf("element %d at path '%s' is missing field '%s'", i, path, field),
+				}
+			}
+
+			if i > 0 {
+				c, ok := compareJson(previous, current)
+				if !ok {
+					return &Result{
+						Type:        Failure,
+						Description: fmt.Sprintf("field '%s' of element %d at path '%s' is not comparable, %s and %s", field, i, path, jsonType(previous), jsonType(current)),
+					}
+				}
+
+				if (order == "asc" && c > 0) || (order == "desc" && c < 0) {
+					return &Result{
+						Type:        Failure,
+						Description: fmt.Sprintf("array at path '%s' is not sorted %s by '%s', element %d (%v) is out of order", path, order, field, i, current),
+					}
+				}
+			}
+			previous = current
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Validates that the reader holds a single json document, decoding it token by token without holding it in memory.
+func validateJsonStream(reader io.Reader) error {
+	decoder := json.NewDecoder(reader)
+	depth := 0
+	values := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if values > 0 {
+			return errors.New("unexpected data after the json document")
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			values++
+		}
+	}
+
+	if values == 0 {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// Assert that the response body is json like BodyIsJson, but validated while streamed instead of read into memory,
+// respecting MaxBodySize, e.g. for very large responses. The body is consumed and is not available to other body
+// assertions or the test function. A non json response body results in a 'Failure'.
+func (r *Request) BodyIsJsonStreaming() *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if r.streamJsonErr != nil {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response body is not json: %s", r.streamJsonErr.Error()),
+				Err:         r.streamJsonErr,
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	r.assertions[len(r.assertions)-1].readsBody = true
+	r.assertions[len(r.assertions)-1].streamsBody = true
+	return r
+}
+
+// Assert that the elements of the json array at the path have distinct values of the field, e.g. no duplicate ids.
+// An empty field compares the elements themselves. A duplicate value, or elements missing the field, results in a 'Failure'
+// reporting the first duplicate.
+func (r *Request) JsonArrayUnique(path, field string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		value, result := r.jsonField(path)
+		if result != nil {
+			return result
+		}
+
+		array, ok := value.([]any)
+		if !ok {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("value at path '%s' is not an array but %s", path, jsonType(value)),
+			}
+		}
+
+		seen := map[string]int{}
+		for i, element := range array {
+			current, ok := jsonLookup(element, field)
+			if !ok {
+				return &Result{
+					Type:        Failure,
+					Description: fmt.Sprintf("element %d at path '%s' is missing field '%s'", i, path, field),
+				}
+			}
+
+			key, err := json.Marshal(current)
+			if err != nil {
+				return &Result{
+					Type:        Error,
+					Description: fmt.Sprintf("failed to marshal field '%s' of element %d: %s", field, i, err.Error()),
+					Err:         err,
+				}
+			}
+
+			if first, ok := seen[string(key)]; ok {
+				return &Result{
+					Type:        Failure,
+					Description: fmt.Sprintf("array at path '%s' has duplicate value %s of '%s' in elements %d and %d", path, key, field, first, i),
+				}
+			}
+			seen[string(key)] = i
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Reformats a json document into a canonical form, compact and with sorted object keys. Numbers are kept as is.
+func normalizeJson(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document any
+	err := decoder.Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, errors.New("trailing data after json document")
+	}
+
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	err = encoder.Encode(document)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+// Converts a go value into its decoded json representation, e.g. an int into a float64.
+func toJson(value any) (any, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded any
+	err = json.Unmarshal(b, &decoded)
+	return decoded, err
+}
+
+// Assert that the json value at the path equals the expected value, compared after encoding the expected value as json.
+// A mismatch in received and expected, or a missing path, results in a 'Failure'.
+func (r *Request) JsonFieldEquals(path string, expected any) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		want, err := toJson(expected)
+		if err != nil {
+			return &Result{
+				Type:        Error,
+				Description: fmt.Sprintf("failed to marshal the expected value: %s", err.Error()),
+				Err:         err,
+			}
+		}
+
+		value, result := r.jsonField(path)
+		if result != nil {
+			return result
+		}
+
+		if !reflect.DeepEqual(value, want) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected value at path '%s', expected '%v' but received '%v'", path, want, value),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+func (r *Request) jsonFieldBool(path string, expected bool) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		value, result := r.jsonField(path)
+		if result != nil {
+			return result
+		}
+
+		b, ok := value.(bool)
+		if !ok {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected type at path '%s', expected bool but received %s", path, jsonType(value)),
+			}
+		}
+
+		if b != expected {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected value at path '%s', expected '%t' but received '%t'", path, expected, b),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the json value at the path is true. A false or non boolean value, or a missing path, results in a 'Failure'.
+func (r *Request) JsonFieldTrue(path string) *Request {
+	return r.jsonFieldBool(path, true)
+}
+
+// Assert that the json value at the path is false. A true or non boolean value, or a missing path, results in a 'Failure'.
+func (r *Request) JsonFieldFalse(path string) *Request {
+	return r.jsonFieldBool(path, false)
+}
+
+// Assert that the whole decoded json response body equals the expected value, e.g. a bare number, bool or string,
+// compared after encoding the expected value as json. A mismatch, or a non json body, results in a 'Failure'.
+func (r *Request) JsonEquals(expected any) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		want, err := toJson(expected)
+		if err != nil {
+			return &Result{
+				Type:        Error,
+				Description: fmt.Sprintf("failed to marshal the expected value: %s", err.Error()),
+				Err:         err,
+			}
+		}
+
+		value, result := r.jsonField("")
+		if result != nil {
+			return result
+		}
+
+		if !reflect.DeepEqual(value, want) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected json body, expected '%v' but received '%v'", want, value),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the json string at the path contains the substring, e.g. for asserting on messages without exact equality.
+// A string not containing it, a value which is not a string, or a missing path, results in a 'Failure'.
+func (r *Request) JsonFieldContains(path, substr string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		value, result := r.jsonField(path)
+		if result != nil {
+			return result
+		}
+
+		str, ok := value.(string)
+		if !ok {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("value at path '%s' is not a string, received %s", path, jsonType(value)),
+			}
+		}
+
+		if !strings.Contains(str, substr) {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("value at path '%s' does not contain '%s', received '%s'", path, substr, str),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the RFC 3339 timestamp at the path is within the duration of the local time, e.g. for freshness checks.
+// A timestamp further away, a value which can not be parsed, or a missing path, results in a 'Failure'.
+func (r *Request) JsonFieldDateWithin(path string, d time.Duration) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		value, result := r.jsonField(path)
+		if result != nil {
+			return result
+		}
+
+		str, ok := value.(string)
+		if !ok {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("value at path '%s' is not a string, received %s", path, jsonType(value)),
+			}
+		}
+
+		date, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("failed to parse the value at path '%s' as an RFC 3339 timestamp: '%s'", path, str),
+				Err:         err,
+			}
+		}
+
+		if diff := time.Since(date).Abs(); diff > d {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("timestamp '%s' at path '%s' differs from local time by %s, expected within %s", str, path, diff.Round(time.Second), d),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assertions scoped to a json path, e.g. an element of an array. The request is embedded to allow continued chaining.
+type JsonScope struct {
+	*Request
+	path string
+}
+
+// Returns assertions scoped to the element at the index of the json array at the path.
+func (r *Request) JsonArrayElement(path string, index int) *JsonScope {
+	return &JsonScope{
+		Request: r,
+		path:    joinJsonPath(path, strconv.Itoa(index)),
+	}
+}
+
+func joinJsonPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Assert that the field, relative to the scope, equals the expected value. See JsonFieldEquals.
+func (s *JsonScope) FieldEquals(field string, expected any) *JsonScope {
+	s.Request.JsonFieldEquals(joinJsonPath(s.path, field), expected)
+	return s
+}
+
+// Extract the json value at the path into the downstream arg with the key. Strings are extracted as is, other values as json.
+// A missing path results in a 'Failure'. Like ExtractHeader, extracting is not counted as an assertion.
+func (r *Request) ExtractJson(argKey, path string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		value, result := r.jsonField(path)
+		if result != nil {
+			return result
+		}
+
+		if s, ok := value.(string); ok {
+			r.extracted[argKey] = s
+		} else {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return &Result{
+					Type:        Error,
+					Description: fmt.Sprintf("failed to marshal value at path '%s': %s", path, err.Error()),
+					Err:         err,
+				}
+			}
+			r.extracted[argKey] = string(b)
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	r.assertions[len(r.assertions)-1].extracts = true
+	return r
+}
+
+// Assert that the path does not exist in the json response body, e.g. that a password field is not leaked.
+// A path which exists results in a 'Failure', also when its value is null, as the field is present.
+func (r *Request) JsonFieldAbsent(path string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		value, ok, err := jsonPath(r.responseBody, path)
+		if err != nil {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("failed to unmarshal the response body: '%s'", r.responseBody),
+				Err:         err,
+			}
+		}
+
+		if ok {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("path '%s' exists in the response body with value '%v'", path, value),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the json object at the path has exactly the keys, no more and no fewer, e.g. for strict contract checks.
+// An empty path refers to the whole body. Extra or missing keys, or a value which is not an object, results in a 'Failure'.
+func (r *Request) JsonOnlyKeys(path string, keys ...string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		value, result := r.jsonField(path)
+		if result != nil {
+			return result
+		}
+
+		object, ok := value.(map[string]any)
+		if !ok {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("value at path '%s' is not an object, received %s", path, jsonType(value)),
+			}
+		}
+
+		var extra, missing []string
+		for key := range object {
+			if !slices.Contains(keys, key) {
+				extra = append(extra, key)
+			}
+		}
+		for _, key := range keys {
+			if _, ok := object[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+
+		if len(extra) > 0 || len(missing) > 0 {
+			slices.Sort(extra)
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected keys at path '%s', extra keys [%s] and missing keys [%s]", path, strings.Join(extra, ", "), strings.Join(missing, ", ")),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Returns true if the json documents are semantically equal, i.e. equal regardless of formatting and object key order.
+func jsonEqual(a, b []byte) (bool, error) {
+	var x, y any
+	err := json.Unmarshal(a, &x)
+	if err != nil {
+		return false, err
+	}
+
+	err = json.Unmarshal(b, &y)
+	if err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(x, y), nil
+}
+
+// Assert that the json response body semantically equals the baseline stored at the path. If no baseline exists,
+// as on the first run, the body is stored as the baseline. A body differing from the baseline results in a 'Failure'.
+func (r *Request) BaselineJson(path string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		baseline, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			if !json.Valid(r.responseBody) {
+				return &Result{
+					Type:        Failure,
+					Description: fmt.Sprintf("failed to unmarshal the response body: '%s'", r.responseBody),
+				}
+			}
+
+			err = os.WriteFile(path, r.responseBody, 0o644)
+			if err != nil {
+				return &Result{
+					Type:        Error,
+					Description: fmt.Sprintf("failed to write baseline: %s", err.Error()),
+					Err:         err,
+				}
+			}
+
+			return &Result{
+				Type: Success,
+			}
+		} else if err != nil {
+			return &Result{
+				Type:        Error,
+				Description: fmt.Sprintf("failed to read baseline: %s", err.Error()),
+				Err:         err,
+			}
+		}
+
+		equal, err := jsonEqual(baseline, r.responseBody)
+		if err != nil {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("failed to compare the response body to the baseline: %s", err.Error()),
+				Err:         err,
+			}
+		}
+
+		if !equal {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response body differs from baseline '%s', expected '%s' but received '%s'", path, baseline, r.responseBody),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the response body matches any of the golden files at the paths, e.g. for responses varying between environments.
+// Json bodies are compared semantically, other bodies byte by byte. A body matching none results in a 'Failure',
+// a golden file which can not be read in an 'Error'.
+func (r *Request) MatchesAnyGolden(paths ...string) *Request {
+	r.addBodyAssertion(func(r *Request, response *http.Response) *Result {
+		for _, path := range paths {
+			golden, err := os.ReadFile(path)
+			if err != nil {
+				return &Result{
+					Type:        Error,
+					Description: fmt.Sprintf("failed to read golden file: %s", err.Error()),
+					Err:         err,
+				}
+			}
+
+			if equal, err := jsonEqual(golden, r.responseBody); err == nil && equal {
+				return &Result{
+					Type: Success,
+				}
+			}
+
+			if bytes.Equal(golden, r.responseBody) {
+				return &Result{
+					Type: Success,
+				}
+			}
+		}
+
+		return &Result{
+			Type:        Failure,
+			Description: fmt.Sprintf("response body matches none of the golden files %s, received '%s'", strings.Join(paths, ", "), r.responseBody),
+		}
+	})
+	return r
+}
@@ -0,0 +1,708 @@
+package jobbigt
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newJsonServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+}
+
+func TestJsonPath(t *testing.T) {
+	body := []byte(`{"a":{"b":[1,{"c":"value"}]},"n":null}`)
+
+	for id, tc := range []struct {
+		Path     string
+		Expected any
+		Found    bool
+	}{
+		{Path: "a.b.1.c", Expected: "value", Found: true},
+		{Path: "a.b.0", Expected: 1.0, Found: true},
+		{Path: "n", Expected: nil, Found: true},
+		{Path: "a.b.2", Found: false},
+		{Path: "a.x", Found: false},
+		{Path: "a.b.1.c.d", Found: false},
+	} {
+		value, found, err := jsonPath(body, tc.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if found != tc.Found || value != tc.Expected {
+			t.Errorf("(%d) received unexpected value for path '%s': %v, %t", id, tc.Path, value, found)
+		}
+	}
+}
+
+func TestJsonFieldIsTypeAssertion(t *testing.T) {
+	testServer := newJsonServer(`{"x":true}`)
+
+	for id, tc := range []struct {
+		Path            string
+		Type            string
+		ExpectedFailure bool
+	}{
+		{
+			Path:            "x",
+			Type:            "bool",
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "x",
+			Type:            "string",
+			ExpectedFailure: true,
+		},
+		{
+			Path:            "y",
+			Type:            "bool",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			JsonFieldIsType(tc.Path, tc.Type).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestJsonArraySortedByAssertion(t *testing.T) {
+	for id, tc := range []struct {
+		Body            string
+		Order           string
+		ExpectedFailure bool
+	}{
+		{
+			Body:            `{"items":[{"id":1},{"id":2},{"id":3}]}`,
+			Order:           "asc",
+			ExpectedFailure: false,
+		},
+		{
+			Body:            `{"items":[{"id":"c"},{"id":"b"},{"id":"a"}]}`,
+			Order:           "desc",
+			ExpectedFailure: false,
+		},
+		{
+			Body:            `{"items":[{"id":1},{"id":3},{"id":2}]}`,
+			Order:           "asc",
+			ExpectedFailure: true,
+		},
+		{
+			Body:            `{"items":[{"id":1},{"name":"x"}]}`,
+			Order:           "asc",
+			ExpectedFailure: true,
+		},
+		{
+			Body:            `{"items":[{"id":1},{"id":"2"}]}`,
+			Order:           "asc",
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := newJsonServer(tc.Body)
+
+		result := Get(testServer.URL).
+			JsonArraySortedBy("items", "id", tc.Order).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestJsonArrayUniqueAssertion(t *testing.T) {
+	for id, tc := range []struct {
+		Body            string
+		Field           string
+		ExpectedFailure bool
+	}{
+		{
+			Body:            `{"items":[{"id":1},{"id":2},{"id":"1"}]}`,
+			Field:           "id",
+			ExpectedFailure: false,
+		},
+		{
+			Body:            `{"items":[{"id":1},{"id":2},{"id":1}]}`,
+			Field:           "id",
+			ExpectedFailure: true,
+		},
+		{
+			Body:            `{"items":["a","b","a"]}`,
+			Field:           "",
+			ExpectedFailure: true,
+		},
+		{
+			Body:            `{"items":[{"id":1},{"name":"x"}]}`,
+			Field:           "id",
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := newJsonServer(tc.Body)
+		result := Get(testServer.URL).
+			JsonArrayUnique("items", tc.Field).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestNormalizeJson(t *testing.T) {
+	testServer := newJsonServer(`{
+		"b": [1, 2.50],
+		"a": "<value>"
+	}`)
+
+	for id, tc := range []struct {
+		Normalize       bool
+		Expected        string
+		ExpectedFailure bool
+	}{
+		{
+			Normalize:       true,
+			Expected:        `{"a":"<value>","b":[1,2.50]}`,
+			ExpectedFailure: false,
+		},
+		{
+			Normalize:       true,
+			Expected:        `{"b": [1, 2.50], "a": "<value>"}`,
+			ExpectedFailure: false,
+		},
+		{
+			Normalize:       false,
+			Expected:        `{"a":"<value>","b":[1,2.50]}`,
+			ExpectedFailure: true,
+		},
+	} {
+		r := Get(testServer.URL)
+		if tc.Normalize {
+			r.NormalizeJson()
+		}
+
+		result := r.BodyEquals([]byte(tc.Expected)).Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestJsonFieldEqualsAssertion(t *testing.T) {
+	testServer := newJsonServer(`{"a":{"b":5,"c":[1,"x"]}}`)
+
+	for id, tc := range []struct {
+		Path            string
+		Expected        any
+		ExpectedFailure bool
+	}{
+		{
+			Path:            "a.b",
+			Expected:        5,
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "a.c",
+			Expected:        []any{1, "x"},
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "a.b",
+			Expected:        "5",
+			ExpectedFailure: true,
+		},
+		{
+			Path:            "a.d",
+			Expected:        5,
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			JsonFieldEquals(tc.Path, tc.Expected).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestJsonEqualsAssertion(t *testing.T) {
+	for id, tc := range []struct {
+		Body            string
+		Expected        any
+		ExpectedFailure bool
+	}{
+		{
+			Body:            `42`,
+			Expected:        42.0,
+			ExpectedFailure: false,
+		},
+		{
+			Body:            `true`,
+			Expected:        true,
+			ExpectedFailure: false,
+		},
+		{
+			Body:            `{"b":[1,2],"a":"x"}`,
+			Expected:        map[string]any{"a": "x", "b": []int{1, 2}},
+			ExpectedFailure: false,
+		},
+		{
+			Body:            `42`,
+			Expected:        "42",
+			ExpectedFailure: true,
+		},
+		{
+			Body:            `not json`,
+			Expected:        42,
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := newJsonServer(tc.Body)
+		result := Get(testServer.URL).
+			JsonEquals(tc.Expected).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestJsonFieldSkipIfMissing(t *testing.T) {
+	testServer := newJsonServer(`{"a":{"b":5}}`)
+
+	for id, tc := range []struct {
+		Request         *Request
+		ExpectedFailure bool
+	}{
+		{
+			Request:         Get(testServer.URL).JsonFieldEquals("a.missing", 5).SkipIfMissing().StatusCode(http.StatusOK),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).JsonFieldEquals("a.b", 6).SkipIfMissing(),
+			ExpectedFailure: true,
+		},
+		{
+			Request:         Get(testServer.URL).JsonFieldEquals("a.missing", 5),
+			ExpectedFailure: true,
+		},
+	} {
+		result := tc.Request.Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestJsonFieldBoolAssertions(t *testing.T) {
+	testServer := newJsonServer(`{"active":true,"deleted":false,"count":1,"flag":"true"}`)
+
+	for id, tc := range []struct {
+		Request         *Request
+		ExpectedFailure bool
+	}{
+		{
+			Request:         Get(testServer.URL).JsonFieldTrue("active"),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).JsonFieldFalse("deleted"),
+			ExpectedFailure: false,
+		},
+		{
+			Request:         Get(testServer.URL).JsonFieldTrue("deleted"),
+			ExpectedFailure: true,
+		},
+		{
+			Request:         Get(testServer.URL).JsonFieldFalse("active"),
+			ExpectedFailure: true,
+		},
+		{
+			Request:         Get(testServer.URL).JsonFieldTrue("count"),
+			ExpectedFailure: true,
+		},
+		{
+			Request:         Get(testServer.URL).JsonFieldTrue("flag"),
+			ExpectedFailure: true,
+		},
+		{
+			Request:         Get(testServer.URL).JsonFieldFalse("missing"),
+			ExpectedFailure: true,
+		},
+	} {
+		result := tc.Request.Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestJsonFieldContainsAssertion(t *testing.T) {
+	testServer := newJsonServer(`{"msg":"hello world","code":500}`)
+
+	for id, tc := range []struct {
+		Path            string
+		Substr          string
+		ExpectedFailure bool
+	}{
+		{
+			Path:            "msg",
+			Substr:          "world",
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "msg",
+			Substr:          "goodbye",
+			ExpectedFailure: true,
+		},
+		{
+			Path:            "code",
+			Substr:          "500",
+			ExpectedFailure: true,
+		},
+		{
+			Path:            "missing",
+			Substr:          "world",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			JsonFieldContains(tc.Path, tc.Substr).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestJsonFieldDateWithinAssertion(t *testing.T) {
+	for id, tc := range []struct {
+		Body            string
+		ExpectedFailure bool
+	}{
+		{
+			Body:            fmt.Sprintf(`{"updated":"%s"}`, time.Now().Add(-time.Minute).Format(time.RFC3339)),
+			ExpectedFailure: false,
+		},
+		{
+			Body:            fmt.Sprintf(`{"updated":"%s"}`, time.Now().Add(-48*time.Hour).Format(time.RFC3339)),
+			ExpectedFailure: true,
+		},
+		{
+			Body:            `{"updated":"yesterday"}`,
+			ExpectedFailure: true,
+		},
+		{
+			Body:            `{"updated":1700000000}`,
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := newJsonServer(tc.Body)
+		result := Get(testServer.URL).
+			JsonFieldDateWithin("updated", time.Hour).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestJsonArrayElement(t *testing.T) {
+	testServer := newJsonServer(`{"items":[{"id":5,"name":"first"},{"id":6}]}`)
+
+	for id, tc := range []struct {
+		Index           int
+		ExpectedFailure bool
+	}{
+		{
+			Index:           0,
+			ExpectedFailure: false,
+		},
+		{
+			Index:           1,
+			ExpectedFailure: true,
+		},
+		{
+			Index:           2,
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			JsonArrayElement("items", tc.Index).
+			FieldEquals("id", 5).
+			FieldEquals("name", "first").
+			StatusCode(http.StatusOK).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestExtractJson(t *testing.T) {
+	testServer := newJsonServer(`{"token":"secret","user":{"id":42}}`)
+
+	result := Get(testServer.URL).
+		ExtractJson("token", "token").
+		ExtractJson("id", "user.id").
+		Run()
+
+	if result.Type != NoTest || result.DownStreamArgs["token"] != "secret" || result.DownStreamArgs["id"] != "42" {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	result = Get(testServer.URL).
+		ExtractJson("missing", "user.name").
+		Run()
+
+	if result.Type != Failure {
+		t.Errorf("received unexpected result type, expected '%d', got '%d'", Failure, result.Type)
+	}
+}
+
+func TestJsonFieldAbsentAssertion(t *testing.T) {
+	testServer := newJsonServer(`{"a":1,"n":null}`)
+
+	for id, tc := range []struct {
+		Path            string
+		ExpectedFailure bool
+	}{
+		{
+			Path:            "b",
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "a.b",
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "a",
+			ExpectedFailure: true,
+		},
+		{
+			Path:            "n",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			JsonFieldAbsent(tc.Path).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestJsonOnlyKeysAssertion(t *testing.T) {
+	testServer := newJsonServer(`{"user":{"id":5,"name":"x"},"items":[]}`)
+
+	for id, tc := range []struct {
+		Path            string
+		Keys            []string
+		ExpectedFailure bool
+	}{
+		{
+			Path:            "user",
+			Keys:            []string{"name", "id"},
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "",
+			Keys:            []string{"user", "items"},
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "user",
+			Keys:            []string{"id"},
+			ExpectedFailure: true,
+		},
+		{
+			Path:            "user",
+			Keys:            []string{"id", "name", "email"},
+			ExpectedFailure: true,
+		},
+		{
+			Path:            "items",
+			Keys:            []string{},
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL).
+			JsonOnlyKeys(tc.Path, tc.Keys...).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestBaselineJson(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+
+	for id, tc := range []struct {
+		Body            string
+		ExpectedFailure bool
+	}{
+		{
+			Body:            `{"a": 1, "b": [1, 2]}`,
+			ExpectedFailure: false,
+		},
+		{
+			Body:            `{"b":[1,2],"a":1}`,
+			ExpectedFailure: false,
+		},
+		{
+			Body:            `{"a": 2, "b": [1, 2]}`,
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := newJsonServer(tc.Body)
+
+		result := Get(testServer.URL).
+			BaselineJson(path).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+
+		if id == 0 {
+			if _, err := os.Stat(path); err != nil {
+				t.Fatalf("expected baseline to be created: %s", err)
+			}
+		}
+	}
+}
+
+func TestBodyIsJsonStreaming(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("["))
+		for i := 0; i < 100000; i++ {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			fmt.Fprintf(w, `{"id":%d,"name":"item %d"}`, i, i)
+		}
+		if r.URL.Path != "/truncated" {
+			w.Write([]byte("]"))
+		}
+	}))
+
+	for id, tc := range []struct {
+		Path            string
+		ExpectedFailure bool
+	}{
+		{
+			Path:            "/",
+			ExpectedFailure: false,
+		},
+		{
+			Path:            "/truncated",
+			ExpectedFailure: true,
+		},
+	} {
+		result := Get(testServer.URL + tc.Path).
+			BodyIsJsonStreaming().
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+
+	result := Get(testServer.URL).
+		MaxBodySize(1024).
+		BodyIsJsonStreaming().
+		Run()
+
+	if result.Type != Error || !errors.Is(result.Err, ErrBodyTooLarge) {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}
+
+func TestValidateJsonStream(t *testing.T) {
+	for id, tc := range []struct {
+		Body          string
+		ExpectedValid bool
+	}{
+		{
+			Body:          `{"a":[1,2,{"b":null}]}`,
+			ExpectedValid: true,
+		},
+		{
+			Body:          `42`,
+			ExpectedValid: true,
+		},
+		{
+			Body:          ``,
+			ExpectedValid: false,
+		},
+		{
+			Body:          `{"a":1}{"b":2}`,
+			ExpectedValid: false,
+		},
+		{
+			Body:          `{"a":}`,
+			ExpectedValid: false,
+		},
+	} {
+		err := validateJsonStream(strings.NewReader(tc.Body))
+		if (err == nil) != tc.ExpectedValid {
+			t.Errorf("(%d) received unexpected validation of '%s': %v", id, tc.Body, err)
+		}
+	}
+}
+
+func TestMatchesAnyGolden(t *testing.T) {
+	dir := t.TempDir()
+	staging := filepath.Join(dir, "staging.json")
+	production := filepath.Join(dir, "production.json")
+	os.WriteFile(staging, []byte(`{"env":"staging","id":1}`), 0o644)
+	os.WriteFile(production, []byte(`{"id": 1, "env": "production"}`), 0o644)
+
+	for id, tc := range []struct {
+		Body            string
+		ExpectedFailure bool
+	}{
+		{
+			Body:            `{"env":"production","id":1}`,
+			ExpectedFailure: false,
+		},
+		{
+			Body:            `{"env":"development","id":1}`,
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := newJsonServer(tc.Body)
+		result := Get(testServer.URL).
+			MatchesAnyGolden(staging, production).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package jobbigt
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Parses RFC 5988 Link header values into a map of relation to url. The first link of a relation takes precedence.
+func parseLinks(values []string) map[string]string {
+	links := map[string]string{}
+	for _, value := range values {
+		for value != "" {
+			start := strings.Index(value, "<")
+			end := strings.Index(value, ">")
+			if start < 0 || end < start {
+				break
+			}
+			url := value[start+1 : end]
+
+			params := value[end+1:]
+			next := strings.Index(params, "<")
+			if next >= 0 {
+				value = params[next:]
+				params = params[:next]
+			} else {
+				value = ""
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				key, rels, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+					continue
+				}
+
+				rels = strings.Trim(rels, `" ,`)
+				for _, rel := range strings.Fields(rels) {
+					rel = strings.ToLower(rel)
+					if _, ok := links[rel]; !ok {
+						links[rel] = url
+					}
+				}
+			}
+		}
+	}
+	return links
+}
+
+// Assert that the response has a Link header with the relation next. A response without it results in a 'Failure'.
+func (r *Request) HasNextPageLink() *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		if _, ok := parseLinks(response.Header.Values("Link"))["next"]; !ok {
+			return &Result{
+				Type:        Failure,
+				Description: "response has no link with relation 'next'",
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
+
+// Assert that the Link header of the response has a link of the relation with a certain url.
+// A missing relation, or a mismatch in received and expected url, results in a 'Failure'.
+func (r *Request) LinkRelEquals(rel, url string) *Request {
+	r.addAssertion(func(r *Request, response *http.Response) *Result {
+		received, ok := parseLinks(response.Header.Values("Link"))[strings.ToLower(rel)]
+		if !ok {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("response has no link with relation '%s'", rel),
+			}
+		}
+
+		if received != url {
+			return &Result{
+				Type:        Failure,
+				Description: fmt.Sprintf("received unexpected url of link with relation '%s', expected '%s' but received '%s'", rel, url, received),
+			}
+		}
+
+		return &Result{
+			Type: Success,
+		}
+	})
+	return r
+}
@@ -0,0 +1,87 @@
+package jobbigt
+
+import (
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	links := parseLinks([]string{
+		`<https://api.example.com/items?page=2>; rel="next", <https://api.example.com/items?page=5>; rel="last"`,
+		`<https://api.example.com/items?page=1>; title="first, page"; rel="first prev"`,
+	})
+
+	expected := map[string]string{
+		"next":  "https://api.example.com/items?page=2",
+		"last":  "https://api.example.com/items?page=5",
+		"first": "https://api.example.com/items?page=1",
+		"prev":  "https://api.example.com/items?page=1",
+	}
+	if !maps.Equal(links, expected) {
+		t.Errorf("received unexpected links: %v", links)
+	}
+}
+
+func TestLinkAssertions(t *testing.T) {
+	next := "https://api.example.com/items?page=2"
+
+	for id, tc := range []struct {
+		Link            string
+		ExpectedFailure bool
+	}{
+		{
+			Link:            `<` + next + `>; rel="next"`,
+			ExpectedFailure: false,
+		},
+		{
+			Link:            `<https://api.example.com/items?page=3>; rel="next"`,
+			ExpectedFailure: true,
+		},
+		{
+			Link:            `<https://api.example.com/items?page=1>; rel="prev"`,
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", tc.Link)
+		}))
+
+		result := Get(testServer.URL).
+			LinkRelEquals("next", next).
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+
+	for id, tc := range []struct {
+		Link            string
+		ExpectedFailure bool
+	}{
+		{
+			Link:            `<` + next + `>; rel="next"`,
+			ExpectedFailure: false,
+		},
+		{
+			Link:            "",
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tc.Link != "" {
+				w.Header().Set("Link", tc.Link)
+			}
+		}))
+
+		result := Get(testServer.URL).
+			HasNextPageLink().
+			Run()
+
+		if isFailure(result, tc.ExpectedFailure) {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package openapi provides assertions validating responses against OpenAPI 3 specifications.
+// It is kept separate from jobbigt to make the OpenAPI dependency optional.
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"jobbigt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+)
+
+func findOperation(doc *openapi3.T, operationID string) (*routers.Route, error) {
+	for path, item := range doc.Paths.Map() {
+		for method, operation := range item.Operations() {
+			if operation.OperationID == operationID {
+				return &routers.Route{
+					Spec:      doc,
+					Path:      path,
+					PathItem:  item,
+					Method:    method,
+					Operation: operation,
+				}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("operation '%s' not found in spec", operationID)
+}
+
+// Assert that the response conforms to the operation with the id in the OpenAPI 3 spec file, both in status code and body schema.
+// Added using AssertBody, as the body is validated. A non conforming response results in a 'Failure', a spec which cannot be loaded
+// or lacks the operation, or a response without its request, results in an 'Error'.
+func ValidatesAgainstOpenAPI(specPath, operationID string) jobbigt.AssertionFunc {
+	return func(response *http.Response, body []byte) *jobbigt.Result {
+		if response.Request == nil {
+			return &jobbigt.Result{
+				Type:        jobbigt.Error,
+				Description: "response is missing the request, required to find the operation",
+			}
+		}
+
+		ctx := context.Background()
+
+		doc, err := openapi3.NewLoader().LoadFromFile(specPath)
+		if err == nil {
+			err = doc.Validate(ctx)
+		}
+		if err != nil {
+			return &jobbigt.Result{
+				Type:        jobbigt.Error,
+				Description: fmt.Sprintf("failed to load spec: %s", err.Error()),
+				Err:         err,
+			}
+		}
+
+		route, err := findOperation(doc, operationID)
+		if err != nil {
+			return &jobbigt.Result{
+				Type:        jobbigt.Error,
+				Description: err.Error(),
+				Err:         err,
+			}
+		}
+
+		input := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: &openapi3filter.RequestValidationInput{
+				Request: response.Request,
+				Route:   route,
+			},
+			Status: response.StatusCode,
+			Header: response.Header,
+			Options: &openapi3filter.Options{
+				IncludeResponseStatus: true,
+			},
+		}
+		input.SetBodyBytes(body)
+
+		err = openapi3filter.ValidateResponse(ctx, input)
+		if err != nil {
+			return &jobbigt.Result{
+				Type:        jobbigt.Failure,
+				Description: fmt.Sprintf("response does not conform to operation '%s': %s", operationID, err.Error()),
+				Err:         err,
+			}
+		}
+
+		return &jobbigt.Result{
+			Type: jobbigt.Success,
+		}
+	}
+}
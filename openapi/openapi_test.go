@@ -0,0 +1,134 @@
+package openapi
+
+import (
+	"jobbigt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const spec = `{
+	"openapi": "3.0.0",
+	"info": {"title": "test", "version": "1.0.0"},
+	"paths": {
+		"/users/{id}": {
+			"get": {
+				"operationId": "getUser",
+				"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+				"responses": {
+					"200": {
+						"description": "a user",
+						"content": {
+							"application/json": {
+								"schema": {
+									"type": "object",
+									"required": ["id", "name"],
+									"properties": {
+										"id": {"type": "integer"},
+										"name": {"type": "string"}
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}`
+
+func TestValidatesAgainstOpenAPI(t *testing.T) {
+	specPath := filepath.Join(t.TempDir(), "spec.json")
+	err := os.WriteFile(specPath, []byte(spec), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, tc := range []struct {
+		Status       int
+		Body         string
+		OperationID  string
+		ExpectedType jobbigt.ResultType
+	}{
+		{
+			Status:       http.StatusOK,
+			Body:         `{"id": 1, "name": "user"}`,
+			OperationID:  "getUser",
+			ExpectedType: jobbigt.Success,
+		},
+		{
+			Status:       http.StatusOK,
+			Body:         `{"id": "1"}`,
+			OperationID:  "getUser",
+			ExpectedType: jobbigt.Failure,
+		},
+		{
+			Status:       http.StatusNotFound,
+			Body:         `{}`,
+			OperationID:  "getUser",
+			ExpectedType: jobbigt.Failure,
+		},
+		{
+			Status:       http.StatusOK,
+			Body:         `{"id": 1, "name": "user"}`,
+			OperationID:  "missing",
+			ExpectedType: jobbigt.Error,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(tc.Status)
+			w.Write([]byte(tc.Body))
+		}))
+
+		result := jobbigt.Get(testServer.URL + "/users/1").
+			AssertBody(ValidatesAgainstOpenAPI(specPath, tc.OperationID)).
+			Run()
+
+		if result.Type != tc.ExpectedType {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestValidatesAgainstOpenAPIInvalidUse(t *testing.T) {
+	specPath := filepath.Join(t.TempDir(), "spec.json")
+	err := os.WriteFile(specPath, []byte(spec), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1, "name": "user"}`))
+	}))
+
+	result := jobbigt.Get(testServer.URL + "/users/1").
+		SkipBodyRead().
+		AssertBody(ValidatesAgainstOpenAPI(specPath, "getUser")).
+		Run()
+
+	if result.Type != jobbigt.Error {
+		t.Errorf("expected an error when skipping the body read: %v", *result)
+	}
+
+	result = jobbigt.Get("http://placeholder/users/1").
+		Transport(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}, nil
+		})).
+		AssertBody(ValidatesAgainstOpenAPI(specPath, "getUser")).
+		Run()
+
+	if result.Type != jobbigt.Error || !strings.Contains(result.Description, "missing the request") {
+		t.Errorf("expected an error for a response without request: %v", *result)
+	}
+}
+
+type roundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
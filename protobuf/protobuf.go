@@ -0,0 +1,49 @@
+// Package protobuf provides assertions for protobuf encoded response bodies.
+// It is kept separate from jobbigt to make the protobuf dependency optional.
+package protobuf
+
+import (
+	"fmt"
+	"jobbigt"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Assert that the response body can be unmarshaled into msg. A body which fails to decode results in a 'Failure'.
+func BodyIsProto(msg proto.Message) jobbigt.AssertionFunc {
+	return func(response *http.Response, body []byte) *jobbigt.Result {
+		err := proto.Unmarshal(body, msg)
+		if err != nil {
+			return &jobbigt.Result{
+				Type:        jobbigt.Failure,
+				Description: fmt.Sprintf("failed to unmarshal the response body: %s", err.Error()),
+				Err:         err,
+			}
+		}
+
+		return &jobbigt.Result{
+			Type: jobbigt.Success,
+		}
+	}
+}
+
+// Assert that the response body can be unmarshaled into msg and that it equals expected. A mismatch results in a 'Failure'.
+func BodyEqualsProto(msg, expected proto.Message) jobbigt.AssertionFunc {
+	isProto := BodyIsProto(msg)
+	return func(response *http.Response, body []byte) *jobbigt.Result {
+		result := isProto(response, body)
+		if result.Type != jobbigt.Success {
+			return result
+		}
+
+		if !proto.Equal(msg, expected) {
+			return &jobbigt.Result{
+				Type:        jobbigt.Failure,
+				Description: fmt.Sprintf("received unexpected message, expected '%v' but received '%v'", expected, msg),
+			}
+		}
+
+		return result
+	}
+}
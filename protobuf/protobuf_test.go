@@ -0,0 +1,77 @@
+package protobuf
+
+import (
+	"jobbigt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestBodyIsProto(t *testing.T) {
+	for id, tc := range []struct {
+		Body            []byte
+		ExpectedFailure bool
+	}{
+		{
+			Body: func() []byte {
+				b, err := proto.Marshal(wrapperspb.String("value"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return b
+			}(),
+			ExpectedFailure: false,
+		},
+		{
+			Body:            []byte{0xff},
+			ExpectedFailure: true,
+		},
+	} {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(tc.Body)
+		}))
+
+		result := jobbigt.Get(testServer.URL).
+			Assert(BodyIsProto(&wrapperspb.StringValue{})).
+			Run()
+
+		if (result.Type == jobbigt.Failure) != tc.ExpectedFailure {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestBodyEqualsProto(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := proto.Marshal(wrapperspb.String("value"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(b)
+	}))
+
+	for id, tc := range []struct {
+		Expected        proto.Message
+		ExpectedFailure bool
+	}{
+		{
+			Expected:        wrapperspb.String("value"),
+			ExpectedFailure: false,
+		},
+		{
+			Expected:        wrapperspb.String("other"),
+			ExpectedFailure: true,
+		},
+	} {
+		result := jobbigt.Get(testServer.URL).
+			Assert(BodyEqualsProto(&wrapperspb.StringValue{}, tc.Expected)).
+			Run()
+
+		if (result.Type == jobbigt.Failure) != tc.ExpectedFailure {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
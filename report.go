@@ -0,0 +1,63 @@
+package jobbigt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The result of a request run as part of a group.
+type RequestResult struct {
+	Id     string
+	Result *Result
+}
+
+// A report of the results of the requests in a group run.
+type Report struct {
+	Results []*RequestResult
+	// Total number of bytes in the response bodies received by the requests.
+	TotalBytes int
+	// The result of the group, as returned by Run.
+	Result *Result
+}
+
+func (rp *Report) add(id string, result *Result) {
+	if result.Metrics != nil {
+		rp.TotalBytes += result.Metrics.BytesReceived
+	}
+	rp.Results = append(rp.Results, &RequestResult{
+		Id:     id,
+		Result: result,
+	})
+}
+
+// Returns the ids of the requests with a result of the given type.
+func (rp *Report) Ids(t ResultType) []string {
+	var ids []string
+	for _, rr := range rp.Results {
+		if rr.Result.Type == t {
+			ids = append(ids, rr.Id)
+		}
+	}
+	return ids
+}
+
+// Returns the ids of the requests which had no assertions or test function, and thereby tested nothing.
+func (rp *Report) NoTest() []string {
+	return rp.Ids(NoTest)
+}
+
+// Returns a summary with one line per request, flagging requests which tested nothing.
+func (rp *Report) String() string {
+	var b strings.Builder
+	for _, rr := range rp.Results {
+		fmt.Fprintf(&b, "%s: %s", rr.Id, rr.Result.Type)
+		if rr.Result.Type == NoTest {
+			b.WriteString(" (no assertions or test function)")
+		}
+		if rr.Result.Description != "" {
+			fmt.Fprintf(&b, ": %s", rr.Result.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,163 @@
+package jobbigt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReportNoTest(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	group := &RequestGroup{}
+	group.AddRequest(Get(testServer.URL).Id("tested").StatusCode(http.StatusOK))
+	group.AddRequest(Get(testServer.URL).Id("untested"))
+	group.AddRequest(Get(testServer.URL).Id("extracting").ExtractHeader("length", "Content-Length"))
+
+	if group.Report() != nil {
+		t.Error("expected no report before running")
+	}
+
+	group.Run()
+
+	report := group.Report()
+	if len(report.Results) != 3 {
+		t.Fatalf("received unexpected number of results: %d", len(report.Results))
+	}
+
+	if ids := report.NoTest(); !slices.Equal(ids, []string{"untested", "extracting"}) {
+		t.Errorf("received unexpected no test requests: %v", ids)
+	}
+
+	if ids := report.Ids(Success); !slices.Equal(ids, []string{"tested"}) {
+		t.Errorf("received unexpected successful requests: %v", ids)
+	}
+
+	if summary := report.String(); !strings.Contains(summary, "untested: NoTest (no assertions or test function)") {
+		t.Errorf("expected summary to flag untested request:\n%s", summary)
+	}
+}
+
+func TestReportTotalBytes(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			w.Write([]byte("hello"))
+		case "/large":
+			w.Write([]byte(strings.Repeat("a", 1024)))
+		}
+	}))
+
+	group := &RequestGroup{}
+	group.AddRequest(Get(testServer.URL + "/small").StatusCode(http.StatusOK))
+	group.AddRequest(Get(testServer.URL + "/large").StatusCode(http.StatusOK))
+
+	group.Run()
+
+	if total := group.Report().TotalBytes; total != 1029 {
+		t.Errorf("received unexpected total bytes: %d", total)
+	}
+}
+
+func TestRunStream(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	group := &RequestGroup{}
+	group.AddRequest(Get(testServer.URL).StatusCode(http.StatusOK))
+	group.AddRequest(Get(testServer.URL + "/fail").StatusCode(http.StatusOK))
+	group.AddRequest(Get(testServer.URL))
+
+	var types []ResultType
+	for result := range group.RunStream() {
+		types = append(types, result.Type)
+	}
+
+	if !slices.Equal(types, []ResultType{Success, Failure, NoTest}) {
+		t.Errorf("received unexpected results: %v", types)
+	}
+
+	if result := group.Report().Result; result == nil || result.Type != Success {
+		t.Errorf("expected the group result in the report, received %v", result)
+	}
+
+	if len(group.Report().Results) != 3 {
+		t.Errorf("expected report after stream is closed, received %v", group.Report())
+	}
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	var buf bytes.Buffer
+	group := (&RequestGroup{}).StreamNDJSON(&buf)
+	group.AddRequest(Get(testServer.URL).Id("first").StatusCode(http.StatusOK).ExtractHeader("length", "Content-Length"))
+	group.AddRequest(Get(testServer.URL + "/fail").Id("second").StatusCode(http.StatusOK))
+	group.Run()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one line per request, received: %s", buf.String())
+	}
+
+	var results []RequestResult
+	for _, line := range lines {
+		var rr RequestResult
+		if err := json.Unmarshal([]byte(line), &rr); err != nil {
+			t.Fatalf("failed to parse line '%s': %v", line, err)
+		}
+		results = append(results, rr)
+	}
+
+	if results[0].Id != "first" || results[0].Result.Type != Success || results[0].Result.DownStreamArgs["length"] != "0" {
+		t.Errorf("received unexpected result: %+v", *results[0].Result)
+	}
+	if results[1].Id != "second" || results[1].Result.Type != Failure || results[1].Result.Metrics.StatusCode != http.StatusInternalServerError {
+		t.Errorf("received unexpected result: %+v", *results[1].Result)
+	}
+}
+
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("broken pipe")
+}
+
+func TestStreamNDJSONWriteError(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := &failingWriter{}
+	group := (&RequestGroup{}).StreamNDJSON(w)
+	group.AddRequest(Get(testServer.URL).StatusCode(http.StatusOK))
+	group.AddRequest(Get(testServer.URL).StatusCode(http.StatusOK))
+
+	result := group.Run()
+	if result.Type != Error || !strings.Contains(result.Description, "broken pipe") {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if w.writes != 1 {
+		t.Errorf("expected the stream to stop after the failing write, received %d writes", w.writes)
+	}
+
+	if len(group.Report().Results) != 2 || group.Report().Result != result {
+		t.Errorf("expected all requests to run and the error in the report, received %v", group.Report())
+	}
+}
@@ -0,0 +1,128 @@
+package jobbigt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+)
+
+type awsCredentials struct {
+	accessKey string
+	secretKey string
+	region    string
+	service   string
+}
+
+// Sign the request using AWS Signature Version 4, setting the X-Amz-Date and Authorization headers before the request is sent.
+func (r *Request) SignAWSV4(accessKey, secretKey, region, service string) *Request {
+	r.awsCredentials = &awsCredentials{
+		accessKey: accessKey,
+		secretKey: secretKey,
+		region:    region,
+		service:   service,
+	}
+	return r
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns the canonical query string, sorted by key and value and encoded with %20 for spaces.
+func canonicalQuery(u *url.URL) string {
+	var pairs []string
+	for key, values := range u.Query() {
+		for _, value := range values {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", sigV4Escape(key), sigV4Escape(value)))
+		}
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func sigV4Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// Returns the canonical request and the signed headers of the request.
+func canonicalRequest(request *http.Request, body []byte) (string, string) {
+	host := request.Host
+	if host == "" {
+		host = request.URL.Host
+	}
+
+	headers := map[string]string{
+		"host": host,
+	}
+	for key, values := range request.Header {
+		trimmed := make([]string, 0, len(values))
+		for _, value := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+		}
+		headers[strings.ToLower(key)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := request.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return strings.Join([]string{
+		request.Method,
+		path,
+		canonicalQuery(request.URL),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n"), signedHeaders
+}
+
+// Signs the request at the given time, setting the X-Amz-Date and Authorization headers.
+func (c *awsCredentials) sign(request *http.Request, body []byte, now time.Time) {
+	amzDate := now.UTC().Format(sigV4TimeFormat)
+	date := amzDate[:8]
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Del("Authorization")
+
+	canonical, signedHeaders := canonicalRequest(request, body)
+	scope := strings.Join([]string{date, c.region, c.service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, sha256Hex([]byte(canonical))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, c.service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", sigV4Algorithm, c.accessKey, scope, signedHeaders, signature))
+}
@@ -0,0 +1,66 @@
+package jobbigt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vector get-vanilla from the AWS Signature Version 4 test suite.
+func TestSignAWSV4TestVector(t *testing.T) {
+	request, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("X-Amz-Date", "20150830T123600Z")
+
+	canonical, signedHeaders := canonicalRequest(request, nil)
+	expectedCanonical := strings.Join([]string{
+		"GET",
+		"/",
+		"",
+		"host:example.amazonaws.com",
+		"x-amz-date:20150830T123600Z",
+		"",
+		"host;x-amz-date",
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+	}, "\n")
+	if canonical != expectedCanonical || signedHeaders != "host;x-amz-date" {
+		t.Errorf("received unexpected canonical request:\n%s", canonical)
+	}
+
+	credentials := &awsCredentials{
+		accessKey: "AKIDEXAMPLE",
+		secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		region:    "us-east-1",
+		service:   "service",
+	}
+	credentials.sign(request, nil, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if authorization := request.Header.Get("Authorization"); authorization != expected {
+		t.Errorf("received unexpected authorization header:\n%s", authorization)
+	}
+}
+
+func TestSignAWSV4(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=access/") ||
+			!strings.Contains(authorization, "/eu-north-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=") {
+			t.Errorf("received unexpected authorization header: %s", authorization)
+		}
+
+		if r.Header.Get("X-Amz-Date") == "" {
+			t.Error("did not receive x-amz-date header")
+		}
+	}))
+
+	Post(testServer.URL).
+		Header("Content-Type", "application/json").
+		Body([]byte(`{"key": "value"}`)).
+		SignAWSV4("access", "secret", "eu-north-1", "s3").
+		Run()
+}
@@ -0,0 +1,93 @@
+package jobbigt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// The json schema of a spec file, describing a group of requests.
+type spec struct {
+	Id       string        `json:"id"`
+	Requests []requestSpec `json:"requests"`
+}
+
+type requestSpec struct {
+	Id         string            `json:"id"`
+	URL        string            `json:"url"`
+	Method     string            `json:"method"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+	Timeout    int               `json:"timeout"`
+	Iterations int               `json:"iterations"`
+	Status     int               `json:"status"`
+}
+
+// Loads a group of requests from a json spec file, e.g.
+//
+//	{
+//		"id": "group",
+//		"requests": [
+//			{
+//				"id": "login",
+//				"url": "http://localhost/login",
+//				"method": "POST",
+//				"headers": {"Content-Type": "application/json"},
+//				"body": "{\"user\": \"name\"}",
+//				"status": 200
+//			}
+//		]
+//	}
+//
+// The method defaults to GET, a status sets a StatusCode assertion.
+func LoadSpec(path string) (*RequestGroup, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s spec
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spec: %w", err)
+	}
+
+	group := &RequestGroup{}
+	if s.Id != "" {
+		group.Id(s.Id)
+	}
+
+	for i, rs := range s.Requests {
+		if rs.URL == "" {
+			return nil, fmt.Errorf("request %d in spec: %w", i, ErrURLRequired)
+		}
+
+		method := rs.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		r := newRequest(rs.URL, method)
+		if rs.Id != "" {
+			r.Id(rs.Id)
+		}
+		for key, value := range rs.Headers {
+			r.Header(key, value)
+		}
+		if rs.Body != "" {
+			r.Body([]byte(rs.Body))
+		}
+		if rs.Timeout != 0 {
+			r.Timeout(rs.Timeout)
+		}
+		r.Iterations(rs.Iterations)
+		if rs.Status != 0 {
+			r.StatusCode(rs.Status)
+		}
+
+		group.AddRequest(r)
+	}
+
+	return group, nil
+}
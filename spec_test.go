@@ -0,0 +1,76 @@
+package jobbigt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSpec(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "spec.json")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSpec(t *testing.T) {
+	var received []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		received = append(received, fmt.Sprintf("%s %s %s %s", r.Method, r.URL.Path, r.Header.Get("Key"), b))
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	path := writeSpec(t, fmt.Sprintf(`{
+		"id": "group",
+		"requests": [
+			{"id": "first", "url": "%[1]s/first", "status": 201},
+			{"id": "second", "url": "%[1]s/second", "method": "POST", "headers": {"Key": "value"}, "body": "body", "status": 201}
+		]
+	}`, testServer.URL))
+
+	group, err := LoadSpec(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if group.id != "group" || len(group.requests) != 2 {
+		t.Fatalf("received unexpected group: %v", group)
+	}
+
+	if result := group.Run(); result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	if len(received) != 2 || received[0] != "GET /first  " || received[1] != "POST /second value body" {
+		t.Errorf("received unexpected requests: %q", received)
+	}
+
+	for id, r := range group.requests {
+		if result := r.Run(); result.Type != Success {
+			t.Errorf("(%d) %v", id, *result)
+		}
+	}
+}
+
+func TestLoadSpecInvalid(t *testing.T) {
+	_, err := LoadSpec(writeSpec(t, `{"requests": [{"method": "GET"}]}`))
+	if !errors.Is(err, ErrURLRequired) {
+		t.Errorf("expected url required error, received: %v", err)
+	}
+
+	_, err = LoadSpec(writeSpec(t, `not json`))
+	if err == nil {
+		t.Error("expected error for invalid spec")
+	}
+}
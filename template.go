@@ -0,0 +1,74 @@
+package jobbigt
+
+import (
+	"maps"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Matches placeholders such as {{req.login.token}}.
+var placeholder = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// Replaces any resolvable placeholders in s, unresolvable placeholders are left as is.
+//
+// Placeholders of the form {{req.<id>.<key>}} resolve to the downstream arg key of the earlier request with the id in the same group.
+// Placeholders of the form {{arg:<key>}} resolve to the incoming arg key, i.e. of the args the request is run with, such as
+// downstream args passed to Run or by a repeat, falling back to the downstream args of the earlier requests in the same group.
+func (r *Request) expand(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+
+	return placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		value, ok := r.lookup(placeholder.FindStringSubmatch(match)[1])
+		if !ok {
+			return match
+		}
+		return value
+	})
+}
+
+func (r *Request) lookup(name string) (string, bool) {
+	if rest, ok := strings.CutPrefix(name, "req."); ok {
+		id, key, ok := strings.Cut(rest, ".")
+		if !ok {
+			return "", false
+		}
+
+		value, ok := r.groupArgs[id][key]
+		return value, ok
+	}
+
+	if key, ok := strings.CutPrefix(name, "arg:"); ok {
+		value, ok := r.incomingArgs[key]
+		return value, ok
+	}
+
+	return "", false
+}
+
+// Collects the incoming args of a run, the downstream args of earlier requests in the group overridden by any args of type
+// map[string]string. Later args take precedence.
+func incomingArgs(upstream map[string]string, args []any) map[string]string {
+	incoming := maps.Clone(upstream)
+	if incoming == nil {
+		incoming = map[string]string{}
+	}
+	for _, arg := range args {
+		if m, ok := arg.(map[string]string); ok {
+			maps.Copy(incoming, m)
+		}
+	}
+	return incoming
+}
+
+func (r *Request) expandHeaders() http.Header {
+	headers := make(http.Header, len(r.headers))
+	for key, values := range r.headers {
+		for _, value := range values {
+			headers[key] = append(headers[key], r.expand(value))
+		}
+	}
+	return headers
+}
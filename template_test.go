@@ -0,0 +1,131 @@
+package jobbigt
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	r := Get("")
+	r.groupArgs = map[string]map[string]string{
+		"login": {"token": "secret", "a.b": "dotted"},
+	}
+	r.incomingArgs = map[string]string{"token": "incoming"}
+
+	for id, tc := range []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: "no placeholder", Expected: "no placeholder"},
+		{Input: "Bearer {{req.login.token}}", Expected: "Bearer secret"},
+		{Input: "{{ req.login.a.b }}", Expected: "dotted"},
+		{Input: "{{req.login.missing}}", Expected: "{{req.login.missing}}"},
+		{Input: "{{req.other.token}}", Expected: "{{req.other.token}}"},
+		{Input: "{{unknown}}", Expected: "{{unknown}}"},
+		{Input: "Bearer {{arg:token}}", Expected: "Bearer incoming"},
+		{Input: "{{arg:missing}}", Expected: "{{arg:missing}}"},
+	} {
+		if received := r.expand(tc.Input); received != tc.Expected {
+			t.Errorf("(%d) received unexpected expansion, expected '%s' but received '%s'", id, tc.Expected, received)
+		}
+	}
+}
+
+func TestRequestGroupTemplating(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "42", "token": "secret"}`))
+	})
+	mux.HandleFunc("/users/42", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	testServer := httptest.NewServer(mux)
+
+	login := Post(testServer.URL + "/login").
+		Id("login").
+		Test(func(response *http.Response, args ...any) Result {
+			var body map[string]string
+			err := json.NewDecoder(response.Body).Decode(&body)
+			if err != nil {
+				return Result{
+					Type:        Error,
+					Description: err.Error(),
+				}
+			}
+			return Result{
+				Type:           Success,
+				DownStreamArgs: body,
+			}
+		})
+
+	var fetchResult *Result
+	fetch := Get(testServer.URL+"/users/{{req.login.id}}").
+		Id("fetch").
+		Header("Authorization", "Bearer {{req.login.token}}").
+		StatusCode(http.StatusOK).
+		PostRequest(func(r *Result) *Result {
+			fetchResult = r
+			return r
+		})
+
+	group := &RequestGroup{}
+	group.AddRequest(login)
+	group.AddRequest(fetch)
+	group.Run()
+
+	if fetchResult == nil || fetchResult.Type != Success {
+		t.Errorf("received unexpected result from templated request: %v", fetchResult)
+	}
+
+	if fetch.url != testServer.URL+"/users/{{req.login.id}}" {
+		t.Errorf("expected the request url template to be preserved, received '%s'", fetch.url)
+	}
+
+	if result := fetch.Run(); result.Type != Failure {
+		t.Errorf("expected the group args to be cleared when run alone, received %v", *result)
+	}
+}
+
+func TestArgTemplating(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("X-Token", "secret")
+		default:
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+
+	login := Post(testServer.URL+"/login").
+		ExtractHeader("token", "X-Token")
+	fetch := Get(testServer.URL + "/users").
+		BearerToken("{{arg:token}}").
+		StatusCode(http.StatusOK)
+
+	result := fetch.Run(login.Run().DownStreamArgs)
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	group := &RequestGroup{}
+	group.AddRequest(login)
+	group.AddRequest(fetch)
+
+	result = group.Run()
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+
+	result = fetch.Run()
+	if result.Type != Failure {
+		t.Errorf("expected no incoming args outside the group: %v", *result)
+	}
+}
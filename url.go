@@ -0,0 +1,61 @@
+package jobbigt
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// Returns the url being built, initially parsed from the url of the request. A url which can not be parsed is replaced.
+func (r *Request) urlBuilder() *url.URL {
+	if r.urlParts == nil {
+		parsed, err := url.Parse(r.url)
+		if err != nil {
+			parsed = &url.URL{}
+		}
+		r.urlParts = parsed
+	}
+	return r.urlParts
+}
+
+// Updates the url of the request from the url being built.
+func (r *Request) buildURL(fn func(u *url.URL)) *Request {
+	u := r.urlBuilder()
+	fn(u)
+	r.url = u.String()
+	return r
+}
+
+// Set the scheme of the url, e.g. https.
+func (r *Request) Scheme(scheme string) *Request {
+	return r.buildURL(func(u *url.URL) {
+		u.Scheme = scheme
+	})
+}
+
+// Set the host and port of the url. A port of 0 leaves the port out.
+func (r *Request) HostPort(host string, port int) *Request {
+	return r.buildURL(func(u *url.URL) {
+		u.Host = host
+		if port != 0 {
+			u.Host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+	})
+}
+
+// Set the path of the url, which is escaped as needed. Placeholders in the path are escaped as well and thereby not expanded.
+func (r *Request) Path(path string) *Request {
+	return r.buildURL(func(u *url.URL) {
+		u.Path = path
+		u.RawPath = ""
+	})
+}
+
+// Add a query parameter to the url, which is escaped as needed. Existing parameters with the key are kept.
+func (r *Request) Query(key, value string) *Request {
+	return r.buildURL(func(u *url.URL) {
+		query := u.Query()
+		query.Add(key, value)
+		u.RawQuery = query.Encode()
+	})
+}
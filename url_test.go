@@ -0,0 +1,53 @@
+package jobbigt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestURLBuilder(t *testing.T) {
+	for id, tc := range []struct {
+		Request  *Request
+		Expected string
+	}{
+		{
+			Request:  Get("").Scheme("https").HostPort("host", 8443).Path("/api/v1").Query("x", "1"),
+			Expected: "https://host:8443/api/v1?x=1",
+		},
+		{
+			Request:  Get("http://host/old?a=1").Path("/items/a b").Query("q", "x&y"),
+			Expected: "http://host/items/a%20b?a=1&q=x%26y",
+		},
+		{
+			Request:  Get("http://host:80").HostPort("::1", 8080),
+			Expected: "http://[::1]:8080",
+		},
+	} {
+		if tc.Request.url != tc.Expected {
+			t.Errorf("(%d) expected url '%s' but received '%s'", id, tc.Expected, tc.Request.url)
+		}
+	}
+}
+
+func TestURLBuilderRun(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1" || r.URL.Query().Get("x") != "1" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	host := strings.TrimPrefix(testServer.URL, "http://")
+	result := Get("").
+		Scheme("http").
+		HostPort(host, 0).
+		Path("/api/v1").
+		Query("x", "1").
+		StatusCode(http.StatusOK).
+		Run()
+
+	if result.Type != Success {
+		t.Errorf("received unexpected result: %v", *result)
+	}
+}